@@ -1,13 +1,20 @@
 package main
 
 import (
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
 	"github.com/joaosczip/go-rate-limiter/configs"
+	"github.com/joaosczip/go-rate-limiter/internal/grpc/rls"
+	"github.com/joaosczip/go-rate-limiter/internal/http/gateway"
 	"github.com/joaosczip/go-rate-limiter/internal/http/middlewares"
 	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 )
 
 func listOrders(w http.ResponseWriter, r *http.Request) {
@@ -16,6 +23,12 @@ func listOrders(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("list of orders"))
 }
 
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
 func main() {
 	envConf, err := configs.LoadConfig(".")
 
@@ -29,10 +42,212 @@ func main() {
 		DB:       envConf.RedisDB,
 	})
 
-	rateLimiterConf := ratelimiter.NewRateLimiterConfig(
-		ratelimiter.NewRateLimiterConfigByIP(envConf.MaxRequestsByIP, time.Duration(envConf.BlockUserForByIP)*time.Second),
-		ratelimiter.NewRateLimiterConfigByToken(envConf.MaxRequestsByToken, time.Duration(envConf.BlockUserForByToken)*time.Second, "API_KEY"),
+	strategy, err := ratelimiter.NewStrategy(envConf.Strategy)
+
+	if err != nil {
+		panic(err)
+	}
+
+	configByIP := ratelimiter.NewRateLimiterConfigByIP(envConf.MaxRequestsByIP, time.Duration(envConf.BlockUserForByIP)*time.Second)
+	configByIP.Window = envConf.WindowByIP
+	configByIP.Burst = envConf.BurstByIP
+	configByIP.RefillRate = envConf.RefillRateByIP
+	configByIP.IPv4Prefix = envConf.IPv4PrefixByIP
+	configByIP.IPv6Prefix = envConf.IPv6PrefixByIP
+
+	configByToken := ratelimiter.NewRateLimiterConfigByToken(envConf.MaxRequestsByToken, time.Duration(envConf.BlockUserForByToken)*time.Second, "API_KEY")
+	configByToken.Window = envConf.WindowByToken
+	configByToken.Burst = envConf.BurstByToken
+	configByToken.RefillRate = envConf.RefillRateByToken
+
+	if envConf.QuotaByToken > 0 {
+		location, err := time.LoadLocation(envConf.QuotaTimezone)
+
+		if err != nil {
+			panic(err)
+		}
+
+		configByToken.Quota, err = ratelimiter.NewQuotaConfig(envConf.QuotaByToken, ratelimiter.QuotaPeriod(envConf.QuotaPeriodByToken), location)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if envConf.TokenTiersFile != "" {
+		registry, err := ratelimiter.LoadTokenRegistry(envConf.TokenTiersFile)
+
+		if err != nil {
+			panic(err)
+		}
+
+		configByToken.Registry = registry
+	}
+
+	combinationMode, err := ratelimiter.ParseCombinationMode(envConf.CombinationMode)
+
+	if err != nil {
+		panic(err)
+	}
+
+	rateLimiterConf := ratelimiter.NewRateLimiterConfig(configByIP, configByToken)
+	rateLimiterConf.Strategy = strategy
+	rateLimiterConf.Mode = combinationMode
+
+	if envConf.MaxRequestsGlobal > 0 {
+		if !envConf.RedisAtomicLimiter {
+			panic(errors.New("MAX_REQUESTS_GLOBAL requires REDIS_ATOMIC_LIMITER=true"))
+		}
+
+		rateLimiterConf.ConfigGlobal = ratelimiter.NewRateLimiterConfigGlobal(envConf.MaxRequestsGlobal, time.Duration(envConf.BlockForGlobal)*time.Second)
+		rateLimiterConf.ConfigGlobal.Window = envConf.WindowGlobal
+	}
+
+	var limiter ratelimiter.Limiter
+	limiterOpts := []ratelimiter.Option{
+		ratelimiter.WithKeyPrefix(envConf.KeyPrefix),
+		ratelimiter.WithTimeout(envConf.DatasourceTimeout),
+	}
+
+	if envConf.RedisAtomicLimiter {
+		limiter = ratelimiter.NewRedisLimiter(redisClient, limiterOpts...)
+	} else {
+		limiter = ratelimiter.NewRateLimiter(ratelimiter.NewRedisDatasource(redisClient), ratelimiter.NewTimeSleeper(), limiterOpts...)
+	}
+
+	failurePolicy, err := ratelimiter.ParseFailurePolicy(envConf.FailurePolicy)
+
+	if err != nil {
+		panic(err)
+	}
+
+	limiter = ratelimiter.NewResilientLimiter(
+		limiter,
+		ratelimiter.NewCircuitBreaker(envConf.BreakerThreshold, envConf.BreakerCooldown),
+		failurePolicy,
+		limiterOpts...,
 	)
-	http.Handle("/", middlewares.RateLimiter(listOrders, rateLimiterConf, redisClient))
+
+	resilientLimiter := limiter
+
+	if envConf.MaxWait > 0 {
+		limiter = ratelimiter.NewWaitingLimiter(limiter, ratelimiter.NewTimeSleeper(), envConf.MaxWait)
+	}
+
+	if envConf.RLSAddress != "" {
+		var descriptorRules []rls.DescriptorRule
+
+		if envConf.RLSRulesFile != "" {
+			descriptorRules, err = rls.LoadDescriptorRules(envConf.RLSRulesFile)
+
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		rlsServer, err := rls.NewServer(resilientLimiter, descriptorRules...)
+
+		if err != nil {
+			panic(err)
+		}
+
+		listener, err := net.Listen("tcp", envConf.RLSAddress)
+
+		if err != nil {
+			panic(err)
+		}
+
+		grpcServer := grpc.NewServer()
+		rlsv3.RegisterRateLimitServiceServer(grpcServer, rlsServer)
+		go grpcServer.Serve(listener)
+	}
+
+	trustedProxies := splitList(envConf.TrustedProxies)
+
+	if envConf.AuthzEnabled && len(trustedProxies) == 0 {
+		panic(errors.New("AUTHZ_ENABLED requires the address of the proxy in TRUSTED_PROXIES"))
+	}
+
+	ipResolver, err := middlewares.NewIPResolver(trustedProxies, splitList(envConf.ClientIPHeaders))
+
+	if err != nil {
+		panic(err)
+	}
+
+	var rules []middlewares.Rule
+
+	if envConf.RulesFile != "" {
+		rules, err = middlewares.LoadRules(envConf.RulesFile)
+
+		if err != nil {
+			panic(err)
+		}
+
+		for _, rule := range rules {
+			if len(rule.Config.Buckets) > 0 && !envConf.RedisAtomicLimiter {
+				panic(errors.New("the buckets of rule " + rule.Name + " require REDIS_ATOMIC_LIMITER=true"))
+			}
+
+			rule.Config.Mode = combinationMode
+			rule.Config.ConfigGlobal = rateLimiterConf.ConfigGlobal
+			if rule.Config.Strategy == nil {
+				rule.Config.Strategy = strategy
+			}
+			if rule.Config.ConfigByIP != nil {
+				if rule.Config.ConfigByIP.IPv4Prefix == 0 {
+					rule.Config.ConfigByIP.IPv4Prefix = configByIP.IPv4Prefix
+				}
+				if rule.Config.ConfigByIP.IPv6Prefix == 0 {
+					rule.Config.ConfigByIP.IPv6Prefix = configByIP.IPv6Prefix
+				}
+			}
+			if rule.Config.ConfigByToken != nil {
+				rule.Config.ConfigByToken.Registry = configByToken.Registry
+			}
+		}
+	}
+
+	ruleSet, err := middlewares.NewRuleSet(rules...)
+
+	if err != nil {
+		panic(err)
+	}
+
+	next := listOrders
+
+	if upstreams := splitList(envConf.UpstreamURLs); len(upstreams) > 0 {
+		proxy, err := gateway.NewGateway(upstreams)
+
+		if err != nil {
+			panic(err)
+		}
+
+		next = proxy.ServeHTTP
+	}
+
+	middlewareOpts := []middlewares.Option{
+		middlewares.WithHeaderStyle(middlewares.HeaderStyle(envConf.RateLimitHeaders)),
+		middlewares.WithIPResolver(ipResolver),
+		middlewares.WithRules(ruleSet),
+	}
+
+	var handler http.Handler
+
+	if envConf.AuthzEnabled {
+		handler = middlewares.Authorization(rateLimiterConf, limiter, envConf.AuthzPathPrefix, middlewareOpts...)
+	} else {
+		handler = middlewares.RateLimiterWith(next, rateLimiterConf, limiter, middlewareOpts...)
+
+		if envConf.MaxConcurrentRequests > 0 {
+			handler = middlewares.ConcurrencyLimiter(
+				handler.ServeHTTP,
+				ratelimiter.NewRedisConcurrencyLimiter(redisClient, envConf.ConcurrencyLeaseTTL, limiterOpts...),
+				envConf.MaxConcurrentRequests,
+				middlewares.WithIPResolver(ipResolver),
+			)
+		}
+	}
+
+	http.Handle("/", handler)
 	http.ListenAndServe(":8080", nil)
 }
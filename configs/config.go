@@ -1,18 +1,57 @@
 package configs
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 var cfg *conf
 
 type conf struct {
-	ApiPort             int    `mapstructure:"API_PORT"`
-	MaxRequestsByIP     int    `mapstructure:"MAX_REQUESTS_BY_IP"`
-	BlockUserForByIP    int    `mapstructure:"BLOCK_USER_FOR_BY_IP"`
-	MaxRequestsByToken  int    `mapstructure:"MAX_REQUESTS_BY_TOKEN"`
-	BlockUserForByToken int    `mapstructure:"BLOCK_USER_FOR_BY_TOKEN"`
-	RedisHost           string `mapstructure:"REDIS_HOST"`
-	RedisPassword       string `mapstructure:"REDIS_PASSWORD"`
-	RedisDB             int    `mapstructure:"REDIS_DB"`
+	ApiPort               int           `mapstructure:"API_PORT"`
+	UpstreamURLs          string        `mapstructure:"UPSTREAM_URLS"`
+	AuthzEnabled          bool          `mapstructure:"AUTHZ_ENABLED"`
+	AuthzPathPrefix       string        `mapstructure:"AUTHZ_PATH_PREFIX"`
+	RLSAddress            string        `mapstructure:"RLS_GRPC_ADDRESS"`
+	RLSRulesFile          string        `mapstructure:"RLS_RULES_FILE"`
+	MaxRequestsByIP       int           `mapstructure:"MAX_REQUESTS_BY_IP"`
+	BlockUserForByIP      int           `mapstructure:"BLOCK_USER_FOR_BY_IP"`
+	WindowByIP            time.Duration `mapstructure:"WINDOW_BY_IP"`
+	BurstByIP             int           `mapstructure:"BURST_BY_IP"`
+	RefillRateByIP        float64       `mapstructure:"REFILL_RATE_BY_IP"`
+	IPv4PrefixByIP        int           `mapstructure:"IPV4_PREFIX_BY_IP"`
+	IPv6PrefixByIP        int           `mapstructure:"IPV6_PREFIX_BY_IP"`
+	MaxRequestsByToken    int           `mapstructure:"MAX_REQUESTS_BY_TOKEN"`
+	BlockUserForByToken   int           `mapstructure:"BLOCK_USER_FOR_BY_TOKEN"`
+	WindowByToken         time.Duration `mapstructure:"WINDOW_BY_TOKEN"`
+	BurstByToken          int           `mapstructure:"BURST_BY_TOKEN"`
+	RefillRateByToken     float64       `mapstructure:"REFILL_RATE_BY_TOKEN"`
+	QuotaByToken          int           `mapstructure:"QUOTA_BY_TOKEN"`
+	QuotaPeriodByToken    string        `mapstructure:"QUOTA_PERIOD_BY_TOKEN"`
+	QuotaTimezone         string        `mapstructure:"QUOTA_TIMEZONE"`
+	TokenTiersFile        string        `mapstructure:"TOKEN_TIERS_FILE"`
+	MaxRequestsGlobal     int           `mapstructure:"MAX_REQUESTS_GLOBAL"`
+	BlockForGlobal        int           `mapstructure:"BLOCK_FOR_GLOBAL"`
+	WindowGlobal          time.Duration `mapstructure:"WINDOW_GLOBAL"`
+	Strategy              string        `mapstructure:"RATE_LIMITER_STRATEGY"`
+	RulesFile             string        `mapstructure:"RATE_LIMIT_RULES_FILE"`
+	CombinationMode       string        `mapstructure:"LIMIT_COMBINATION_MODE"`
+	RedisHost             string        `mapstructure:"REDIS_HOST"`
+	RedisPassword         string        `mapstructure:"REDIS_PASSWORD"`
+	RedisDB               int           `mapstructure:"REDIS_DB"`
+	RedisAtomicLimiter    bool          `mapstructure:"REDIS_ATOMIC_LIMITER"`
+	KeyPrefix             string        `mapstructure:"RATE_LIMITER_KEY_PREFIX"`
+	DatasourceTimeout     time.Duration `mapstructure:"DATASOURCE_TIMEOUT"`
+	FailurePolicy         string        `mapstructure:"FAILURE_POLICY"`
+	BreakerThreshold      int           `mapstructure:"CIRCUIT_BREAKER_THRESHOLD"`
+	BreakerCooldown       time.Duration `mapstructure:"CIRCUIT_BREAKER_COOLDOWN"`
+	MaxWait               time.Duration `mapstructure:"MAX_WAIT"`
+	RateLimitHeaders      string        `mapstructure:"RATE_LIMIT_HEADERS"`
+	MaxConcurrentRequests int           `mapstructure:"MAX_CONCURRENT_REQUESTS"`
+	ConcurrencyLeaseTTL   time.Duration `mapstructure:"CONCURRENCY_LEASE_TTL"`
+	TrustedProxies        string        `mapstructure:"TRUSTED_PROXIES"`
+	ClientIPHeaders       string        `mapstructure:"CLIENT_IP_HEADERS"`
 }
 
 func LoadConfig(path string) (*conf, error) {
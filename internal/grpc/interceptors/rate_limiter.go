@@ -0,0 +1,110 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var ErrNilLimiter = errors.New("the limiter is required")
+
+func UnaryServerInterceptor(config *ratelimiter.RateLimiterConfig, limiter ratelimiter.Limiter) (grpc.UnaryServerInterceptor, error) {
+	if err := validate(config, limiter); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limit(ctx, config, limiter); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}, nil
+}
+
+func StreamServerInterceptor(config *ratelimiter.RateLimiterConfig, limiter ratelimiter.Limiter) (grpc.StreamServerInterceptor, error) {
+	if err := validate(config, limiter); err != nil {
+		return nil, err
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limit(ss.Context(), config, limiter); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}, nil
+}
+
+func validate(config *ratelimiter.RateLimiterConfig, limiter ratelimiter.Limiter) error {
+	if config == nil {
+		return ratelimiter.ErrNilConfig
+	}
+	if limiter == nil {
+		return ErrNilLimiter
+	}
+	return nil
+}
+
+func limit(ctx context.Context, config *ratelimiter.RateLimiterConfig, limiter ratelimiter.Limiter) error {
+	ip, ok := peerIP(ctx)
+	if !ok {
+		return status.Error(codes.Internal, "error extracting the peer address from the call")
+	}
+
+	var token = ""
+
+	if config.ConfigByToken != nil {
+		if values := metadata.ValueFromIncomingContext(ctx, strings.ToLower(config.ConfigByToken.Key)); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	decision, err := limiter.HandleRequestContext(ctx, ip, token, config)
+
+	if decision != nil && decision.Limit > 0 {
+		grpc.SetHeader(ctx, metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(decision.Limit),
+			"ratelimit-remaining", strconv.Itoa(decision.Remaining),
+		))
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, ratelimiter.ErrMaxRequests) {
+		exhausted := status.New(codes.ResourceExhausted, err.Error())
+		if decision != nil {
+			if detailed, detailErr := exhausted.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(decision.RetryAfter)}); detailErr == nil {
+				exhausted = detailed
+			}
+		}
+		return exhausted.Err()
+	}
+	if errors.Is(err, ratelimiter.ErrCircuitOpen) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func peerIP(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host, true
+	}
+	return p.Addr.String(), true
+}
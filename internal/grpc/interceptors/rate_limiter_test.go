@@ -0,0 +1,121 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, config *ratelimiter.RateLimiterConfig) healthpb.HealthClient {
+	limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+	t.Cleanup(limiter.Stop)
+
+	unary, err := UnaryServerInterceptor(config, limiter)
+	assert.NoError(t, err)
+	stream, err := StreamServerInterceptor(config, limiter)
+	assert.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestRateLimiterInterceptors(t *testing.T) {
+	t.Run("should return resource exhausted with retry info on unary calls", func(t *testing.T) {
+		client := newClient(t, ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, time.Minute), nil))
+
+		var header metadata.MD
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+		assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+		_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		details := status.Convert(err).Details()
+		assert.Len(t, details, 1)
+		retryInfo, ok := details[0].(*errdetails.RetryInfo)
+		assert.True(t, ok)
+		assert.InDelta(t, time.Minute, retryInfo.RetryDelay.AsDuration(), float64(time.Second))
+	})
+
+	t.Run("should limit by the token sent in the metadata", func(t *testing.T) {
+		config := ratelimiter.NewRateLimiterConfig(
+			ratelimiter.NewRateLimiterConfigByIP(1, time.Minute),
+			ratelimiter.NewRateLimiterConfigByToken(2, time.Minute, "API_KEY"),
+		)
+		client := newClient(t, config)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "api_key", "abc")
+
+		for i := 0; i < 2; i++ {
+			_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+			assert.NoError(t, err)
+		}
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should limit the opening of streams", func(t *testing.T) {
+		client := newClient(t, ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, time.Minute), nil))
+
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+		response, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+
+		stream, err = client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("should fail fast without a config or a limiter", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+		config := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, time.Minute), nil)
+
+		_, err := UnaryServerInterceptor(nil, limiter)
+		assert.ErrorIs(t, err, ratelimiter.ErrNilConfig)
+
+		_, err = StreamServerInterceptor(nil, limiter)
+		assert.ErrorIs(t, err, ratelimiter.ErrNilConfig)
+
+		_, err = UnaryServerInterceptor(config, nil)
+		assert.ErrorIs(t, err, ErrNilLimiter)
+
+		_, err = StreamServerInterceptor(config, nil)
+		assert.ErrorIs(t, err, ErrNilLimiter)
+	})
+}
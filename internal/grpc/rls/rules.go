@@ -0,0 +1,134 @@
+package rls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+)
+
+var (
+	ErrInvalidDescriptorRule   = errors.New("invalid descriptor rule")
+	ErrDuplicateDescriptorRule = errors.New("duplicate descriptor rule")
+)
+
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type DescriptorRule struct {
+	Name     string
+	Domain   string
+	Entries  []Entry
+	Strategy ratelimiter.Strategy
+	Limit    ratelimiter.BaseLimiterConfig
+}
+
+func (rule DescriptorRule) matches(domain string, descriptor *ratelimitv3.RateLimitDescriptor) bool {
+	if rule.Domain != domain || len(rule.Entries) != len(descriptor.GetEntries()) {
+		return false
+	}
+
+	for i, entry := range descriptor.GetEntries() {
+		if rule.Entries[i].Key != entry.GetKey() {
+			return false
+		}
+		if rule.Entries[i].Value != "" && rule.Entries[i].Value != entry.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func (rule DescriptorRule) config() *ratelimiter.RateLimiterConfig {
+	config := ratelimiter.NewRateLimiterConfig(nil, &ratelimiter.RateLimiterConfigByToken{BaseLimiterConfig: rule.Limit})
+	config.Strategy = rule.Strategy
+	config.Scope = rule.scope()
+	return config
+}
+
+func (rule DescriptorRule) scope() string {
+	return url.QueryEscape(rule.Domain) + "/" + url.QueryEscape(rule.Name)
+}
+
+func descriptorKey(descriptor *ratelimitv3.RateLimitDescriptor) string {
+	entries := make([]string, 0, len(descriptor.GetEntries()))
+	for _, entry := range descriptor.GetEntries() {
+		entries = append(entries, url.QueryEscape(entry.GetKey())+"="+url.QueryEscape(entry.GetValue()))
+	}
+	return strings.Join(entries, "&")
+}
+
+type ruleFile struct {
+	Name              string  `json:"name"`
+	Domain            string  `json:"domain"`
+	Entries           []Entry `json:"entries"`
+	Strategy          string  `json:"strategy"`
+	RequestsPerSecond int     `json:"requestsPerSecond"`
+	BlockUserFor      string  `json:"blockUserFor"`
+	Window            string  `json:"window"`
+	Burst             int     `json:"burst"`
+	RefillRate        float64 `json:"refillRate"`
+}
+
+func LoadDescriptorRules(filename string) ([]DescriptorRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ruleFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, err
+	}
+
+	rules := make([]DescriptorRule, 0, len(files))
+	for _, file := range files {
+		rule, err := file.rule()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidDescriptorRule, file.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (f ruleFile) rule() (DescriptorRule, error) {
+	rule := DescriptorRule{
+		Name:    f.Name,
+		Domain:  f.Domain,
+		Entries: f.Entries,
+		Limit: ratelimiter.BaseLimiterConfig{
+			RequestesPerSecond: f.RequestsPerSecond,
+			Burst:              f.Burst,
+			RefillRate:         f.RefillRate,
+		},
+	}
+
+	var err error
+	if f.Strategy != "" {
+		if rule.Strategy, err = ratelimiter.NewStrategy(f.Strategy); err != nil {
+			return rule, err
+		}
+	}
+	if f.BlockUserFor != "" {
+		if rule.Limit.BlockUserFor, err = time.ParseDuration(f.BlockUserFor); err != nil {
+			return rule, err
+		}
+	}
+	if f.Window != "" {
+		if rule.Limit.Window, err = time.ParseDuration(f.Window); err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, nil
+}
@@ -0,0 +1,151 @@
+package rls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var units = map[rlsv3.RateLimitResponse_RateLimit_Unit]time.Duration{
+	rlsv3.RateLimitResponse_RateLimit_SECOND: time.Second,
+	rlsv3.RateLimitResponse_RateLimit_MINUTE: time.Minute,
+	rlsv3.RateLimitResponse_RateLimit_HOUR:   time.Hour,
+	rlsv3.RateLimitResponse_RateLimit_DAY:    24 * time.Hour,
+	rlsv3.RateLimitResponse_RateLimit_MONTH:  30 * 24 * time.Hour,
+	rlsv3.RateLimitResponse_RateLimit_YEAR:   365 * 24 * time.Hour,
+}
+
+type Server struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+	limiter ratelimiter.Limiter
+	rules   []DescriptorRule
+}
+
+func NewServer(limiter ratelimiter.Limiter, rules ...DescriptorRule) (*Server, error) {
+	names := make(map[string]bool)
+
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Domain == "" || len(rule.Entries) == 0 {
+			return nil, fmt.Errorf("%w: a rule needs a name, a domain and entries", ErrInvalidDescriptorRule)
+		}
+		if names[rule.scope()] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateDescriptorRule, rule.Name)
+		}
+		names[rule.scope()] = true
+
+		for _, entry := range rule.Entries {
+			if entry.Key == "" {
+				return nil, fmt.Errorf("%w: %s: an entry needs a key", ErrInvalidDescriptorRule, rule.Name)
+			}
+		}
+	}
+
+	return &Server{limiter: limiter, rules: rules}, nil
+}
+
+func (s *Server) ShouldRateLimit(ctx context.Context, request *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	if request.GetDomain() == "" {
+		return nil, status.Error(codes.InvalidArgument, "the domain is required")
+	}
+
+	response := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+
+	for _, descriptor := range request.GetDescriptors() {
+		descriptorStatus, err := s.evaluate(ctx, request.GetDomain(), descriptor, int(request.GetHitsAddend()))
+		if err != nil {
+			return nil, err
+		}
+
+		if descriptorStatus.Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			response.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+		}
+		response.Statuses = append(response.Statuses, descriptorStatus)
+	}
+
+	return response, nil
+}
+
+func (s *Server) evaluate(ctx context.Context, domain string, descriptor *ratelimitv3.RateLimitDescriptor, cost int) (*rlsv3.RateLimitResponse_DescriptorStatus, error) {
+	if len(descriptor.GetEntries()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "a descriptor needs at least one entry")
+	}
+
+	rule := s.match(domain, descriptor)
+
+	if override := descriptor.GetLimit(); override != nil {
+		if rule == nil {
+			rule = &DescriptorRule{Name: "override", Domain: domain}
+		}
+		window, ok := units[rlsv3.RateLimitResponse_RateLimit_Unit(override.GetUnit())]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported rate limit unit %s", override.GetUnit())
+		}
+		rule.Limit.RequestesPerSecond = int(override.GetRequestsPerUnit())
+		rule.Limit.Window = window
+	}
+
+	if rule == nil {
+		return &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}, nil
+	}
+
+	decision, err := s.limiter.HandleRequestN(ctx, "", descriptorKey(descriptor), rule.config(), cost)
+	if err != nil && !errors.Is(err, ratelimiter.ErrMaxRequests) {
+		if errors.Is(err, ratelimiter.ErrCircuitOpen) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	descriptorStatus := &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}
+	if err != nil {
+		descriptorStatus.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+	}
+	if decision == nil || decision.Limit == 0 {
+		return descriptorStatus, nil
+	}
+
+	descriptorStatus.CurrentLimit = &rlsv3.RateLimitResponse_RateLimit{
+		Name:            rule.Name,
+		RequestsPerUnit: uint32(decision.Limit),
+		Unit:            unitFor(rule.Limit.Window),
+	}
+	descriptorStatus.LimitRemaining = uint32(decision.Remaining)
+
+	if reset := time.Until(decision.Reset); reset > 0 {
+		descriptorStatus.DurationUntilReset = durationpb.New(reset)
+	} else {
+		descriptorStatus.DurationUntilReset = durationpb.New(0)
+	}
+
+	return descriptorStatus, nil
+}
+
+func (s *Server) match(domain string, descriptor *ratelimitv3.RateLimitDescriptor) *DescriptorRule {
+	for i := range s.rules {
+		if s.rules[i].matches(domain, descriptor) {
+			rule := s.rules[i]
+			return &rule
+		}
+	}
+	return nil
+}
+
+func unitFor(window time.Duration) rlsv3.RateLimitResponse_RateLimit_Unit {
+	if window == 0 {
+		return rlsv3.RateLimitResponse_RateLimit_SECOND
+	}
+	for unit, duration := range units {
+		if duration == window {
+			return unit
+		}
+	}
+	return rlsv3.RateLimitResponse_RateLimit_UNKNOWN
+}
@@ -0,0 +1,248 @@
+package rls
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, rules ...DescriptorRule) rlsv3.RateLimitServiceClient {
+	limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+	t.Cleanup(limiter.Stop)
+
+	return newClientWith(t, limiter, rules...)
+}
+
+func newClientWith(t *testing.T, limiter ratelimiter.Limiter, rules ...DescriptorRule) rlsv3.RateLimitServiceClient {
+	server, err := NewServer(limiter, rules...)
+	assert.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	rlsv3.RegisterRateLimitServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return rlsv3.NewRateLimitServiceClient(conn)
+}
+
+func descriptor(entries ...string) *ratelimitv3.RateLimitDescriptor {
+	descriptor := &ratelimitv3.RateLimitDescriptor{}
+	for i := 0; i+1 < len(entries); i += 2 {
+		descriptor.Entries = append(descriptor.Entries, &ratelimitv3.RateLimitDescriptor_Entry{Key: entries[i], Value: entries[i+1]})
+	}
+	return descriptor
+}
+
+func TestServer(t *testing.T) {
+	remoteAddress := DescriptorRule{
+		Name:    "per-ip",
+		Domain:  "edge",
+		Entries: []Entry{{Key: "remote_address"}},
+		Limit:   ratelimiter.BaseLimiterConfig{RequestesPerSecond: 2, Window: time.Minute},
+	}
+
+	t.Run("should return the remaining quota and the reset of each descriptor", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+
+		response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+		assert.Len(t, response.Statuses, 1)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.Statuses[0].Code)
+		assert.Equal(t, uint32(2), response.Statuses[0].CurrentLimit.RequestsPerUnit)
+		assert.Equal(t, rlsv3.RateLimitResponse_RateLimit_MINUTE, response.Statuses[0].CurrentLimit.Unit)
+		assert.Equal(t, uint32(1), response.Statuses[0].LimitRemaining)
+		assert.InDelta(t, time.Minute, response.Statuses[0].DurationUntilReset.AsDuration(), float64(time.Second))
+	})
+
+	t.Run("should return over limit when a descriptor exceeds its limit", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+		request := &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+		}
+
+		for i := 0; i < 2; i++ {
+			response, err := client.ShouldRateLimit(context.Background(), request)
+			assert.NoError(t, err)
+			assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+		}
+
+		response, err := client.ShouldRateLimit(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.OverallCode)
+		assert.Equal(t, uint32(0), response.Statuses[0].LimitRemaining)
+
+		other, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.2")},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, other.OverallCode)
+	})
+
+	t.Run("should use hits_addend as the cost of the request", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+
+		response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")},
+			HitsAddend:  3,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.OverallCode)
+	})
+
+	t.Run("should match the entry values and the domain of the rules", func(t *testing.T) {
+		login := DescriptorRule{
+			Name:    "login",
+			Domain:  "edge",
+			Entries: []Entry{{Key: "path", Value: "/login"}, {Key: "remote_address"}},
+			Limit:   ratelimiter.BaseLimiterConfig{RequestesPerSecond: 1, Window: time.Minute},
+		}
+		client := newClient(t, login)
+
+		response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+			Domain: "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{
+				descriptor("path", "/login", "remote_address", "10.0.0.1"),
+				descriptor("path", "/orders", "remote_address", "10.0.0.1"),
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(1), response.Statuses[0].CurrentLimit.RequestsPerUnit)
+		assert.Nil(t, response.Statuses[1].CurrentLimit)
+
+		response, err = client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+			Domain:      "internal",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("path", "/login", "remote_address", "10.0.0.1")},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+		assert.Nil(t, response.Statuses[0].CurrentLimit)
+	})
+
+	t.Run("should apply the limit override of a descriptor", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+		overridden := descriptor("remote_address", "10.0.0.1")
+		overridden.Limit = &ratelimitv3.RateLimitDescriptor_RateLimitOverride{RequestsPerUnit: 1, Unit: typev3.RateLimitUnit_HOUR}
+		request := &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{overridden}}
+
+		response, err := client.ShouldRateLimit(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_RateLimit_HOUR, response.Statuses[0].CurrentLimit.Unit)
+		assert.Equal(t, uint32(0), response.Statuses[0].LimitRemaining)
+
+		response, err = client.ShouldRateLimit(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, response.OverallCode)
+	})
+
+	t.Run("should support the month and year units", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+
+		for _, unit := range []typev3.RateLimitUnit{typev3.RateLimitUnit_MONTH, typev3.RateLimitUnit_YEAR} {
+			overridden := descriptor("remote_address", "10.0.0.1")
+			overridden.Limit = &ratelimitv3.RateLimitDescriptor_RateLimitOverride{RequestsPerUnit: 10, Unit: unit}
+
+			response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{overridden}})
+			assert.NoError(t, err)
+			assert.Equal(t, rlsv3.RateLimitResponse_RateLimit_Unit(unit), response.Statuses[0].CurrentLimit.Unit)
+		}
+	})
+
+	t.Run("should reject a limit override with an unknown unit", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+		overridden := descriptor("remote_address", "10.0.0.1")
+		overridden.Limit = &ratelimitv3.RateLimitDescriptor_RateLimitOverride{RequestsPerUnit: 1, Unit: typev3.RateLimitUnit_UNKNOWN}
+
+		_, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{overridden}})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should not mix up the keys of descriptors with separators in their values", func(t *testing.T) {
+		assert.NotEqual(t, descriptorKey(descriptor("path", "/a|method=GET")), descriptorKey(descriptor("path", "/a", "method", "GET")))
+		assert.NotEqual(t, descriptorKey(descriptor("path", "/a&method=GET")), descriptorKey(descriptor("path", "/a", "method", "GET")))
+		assert.Equal(t, "path=%2Fa&method=GET", descriptorKey(descriptor("path", "/a", "method", "GET")))
+	})
+
+	t.Run("should reject a descriptor without entries", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+		empty := &ratelimitv3.RateLimitDescriptor{Limit: &ratelimitv3.RateLimitDescriptor_RateLimitOverride{RequestsPerUnit: 1, Unit: typev3.RateLimitUnit_SECOND}}
+
+		_, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{empty}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")}})
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+	})
+
+	t.Run("should not report a limit for fail open decisions", func(t *testing.T) {
+		redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+		t.Cleanup(func() { redisClient.Close() })
+
+		limiter := ratelimiter.NewResilientLimiter(ratelimiter.NewRedisLimiter(redisClient), ratelimiter.NewCircuitBreaker(5, time.Minute), ratelimiter.FailOpen)
+		client := newClientWith(t, limiter, remoteAddress)
+
+		response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "10.0.0.1")}})
+		assert.NoError(t, err)
+		assert.Equal(t, rlsv3.RateLimitResponse_OK, response.OverallCode)
+		assert.Nil(t, response.Statuses[0].CurrentLimit)
+	})
+
+	t.Run("should keep the rules of domains and names with dots apart", func(t *testing.T) {
+		first := DescriptorRule{Name: "c", Domain: "a.b", Entries: []Entry{{Key: "remote_address"}}}
+		second := DescriptorRule{Name: "b.c", Domain: "a", Entries: []Entry{{Key: "remote_address"}}}
+
+		assert.NotEqual(t, first.config().Scope, second.config().Scope)
+
+		_, err := NewServer(nil, first, second)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject a request without a domain", func(t *testing.T) {
+		client := newClient(t, remoteAddress)
+
+		_, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should reject invalid and duplicate rules", func(t *testing.T) {
+		_, err := NewServer(nil, DescriptorRule{Name: "per-ip", Domain: "edge"})
+		assert.ErrorIs(t, err, ErrInvalidDescriptorRule)
+
+		_, err = NewServer(nil, remoteAddress, remoteAddress)
+		assert.ErrorIs(t, err, ErrDuplicateDescriptorRule)
+	})
+}
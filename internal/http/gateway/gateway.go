@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/joaosczip/go-rate-limiter/internal/http/middlewares"
+)
+
+var (
+	ErrNoUpstreams     = errors.New("at least one upstream is required")
+	ErrInvalidUpstream = errors.New("invalid upstream url")
+)
+
+type Gateway struct {
+	upstreams []*url.URL
+	next      atomic.Uint64
+	proxy     *httputil.ReverseProxy
+}
+
+func NewGateway(upstreams []string) (*Gateway, error) {
+	if len(upstreams) == 0 {
+		return nil, ErrNoUpstreams
+	}
+
+	gateway := &Gateway{}
+
+	for _, upstream := range upstreams {
+		target, err := url.Parse(upstream)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidUpstream, err)
+		}
+		if target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidUpstream, upstream)
+		}
+		gateway.upstreams = append(gateway.upstreams, target)
+	}
+
+	gateway.proxy = &httputil.ReverseProxy{
+		Rewrite:       gateway.rewrite,
+		FlushInterval: -1,
+		ErrorHandler:  gateway.handleError,
+	}
+
+	return gateway, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.proxy.ServeHTTP(w, r)
+}
+
+func (g *Gateway) rewrite(r *httputil.ProxyRequest) {
+	r.SetURL(g.upstream())
+	r.Out.Header["X-Forwarded-For"] = r.In.Header["X-Forwarded-For"]
+	r.SetXForwarded()
+}
+
+func (g *Gateway) upstream() *url.URL {
+	return g.upstreams[(g.next.Add(1)-1)%uint64(len(g.upstreams))]
+}
+
+func (g *Gateway) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	fmt.Printf("error forwarding the request to the upstream: %v\n", err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	json.NewEncoder(w).Encode(&middlewares.Response{Message: http.StatusText(http.StatusBadGateway)})
+}
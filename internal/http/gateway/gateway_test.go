@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGateway(t *testing.T) {
+	t.Run("should reject missing or invalid upstreams", func(t *testing.T) {
+		_, err := NewGateway(nil)
+		assert.ErrorIs(t, err, ErrNoUpstreams)
+
+		_, err = NewGateway([]string{"localhost"})
+		assert.ErrorIs(t, err, ErrInvalidUpstream)
+	})
+
+	t.Run("should forward the requests to the upstreams in turn", func(t *testing.T) {
+		var upstreams []string
+		for _, name := range []string{"first", "second"} {
+			name := name
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "%s %s %s", name, r.URL.Path, r.Header.Get("X-Forwarded-For"))
+			}))
+			defer upstream.Close()
+			upstreams = append(upstreams, upstream.URL)
+		}
+
+		gateway, err := NewGateway(upstreams)
+		assert.NoError(t, err)
+
+		for _, expected := range []string{"first /orders 127.0.0.1", "second /orders 127.0.0.1", "first /orders 127.0.0.1"} {
+			r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			r.RemoteAddr = "127.0.0.1:12345"
+			w := httptest.NewRecorder()
+			gateway.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, expected, w.Body.String())
+		}
+	})
+
+	t.Run("should return bad gateway when the upstream is unavailable", func(t *testing.T) {
+		upstream := httptest.NewServer(http.NotFoundHandler())
+		upstream.Close()
+
+		gateway, err := NewGateway([]string{upstream.URL})
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+
+	t.Run("should stream the response without buffering", func(t *testing.T) {
+		release := make(chan struct{})
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "first chunk\n")
+			w.(http.Flusher).Flush()
+			<-release
+			fmt.Fprint(w, "second chunk\n")
+		}))
+		defer upstream.Close()
+
+		gateway, err := NewGateway([]string{upstream.URL})
+		assert.NoError(t, err)
+
+		server := httptest.NewServer(gateway)
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		assert.NoError(t, err)
+		defer response.Body.Close()
+
+		reader := bufio.NewReader(response.Body)
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "first chunk\n", line)
+
+		close(release)
+		rest, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "second chunk\n", string(rest))
+	})
+
+	t.Run("should pass upgraded connections through", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, buffer, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+			buffer.Flush()
+
+			message, _ := buffer.ReadString('\n')
+			buffer.WriteString("echo " + message)
+			buffer.Flush()
+		}))
+		defer upstream.Close()
+
+		gateway, err := NewGateway([]string{upstream.URL})
+		assert.NoError(t, err)
+
+		server := httptest.NewServer(gateway)
+		defer server.Close()
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		assert.NoError(t, err)
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		fmt.Fprint(conn, "GET /socket HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+
+		reader := bufio.NewReader(conn)
+		response, err := http.ReadResponse(reader, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+		fmt.Fprint(conn, "hello\n")
+		echo, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "echo hello\n", echo)
+	})
+}
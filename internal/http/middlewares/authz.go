@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+)
+
+const (
+	HeaderOriginalURI    = "X-Original-Uri"
+	HeaderOriginalMethod = "X-Original-Method"
+	HeaderForwardedHost  = "X-Forwarded-Host"
+)
+
+func allow(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func Authorization(config *ratelimiter.RateLimiterConfig, rateLimiter ratelimiter.Limiter, pathPrefix string, opts ...Option) http.Handler {
+	limited := RateLimiterWith(allow, config, rateLimiter, opts...)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limited.ServeHTTP(w, originalRequest(r, pathPrefix))
+	})
+}
+
+func originalRequest(r *http.Request, pathPrefix string) *http.Request {
+	original := r.Clone(r.Context())
+
+	if uri := r.Header.Get(HeaderOriginalURI); uri != "" {
+		if parsed, err := url.ParseRequestURI(uri); err == nil {
+			original.URL.Path = parsed.Path
+			original.URL.RawPath = parsed.RawPath
+			original.URL.RawQuery = parsed.RawQuery
+		}
+	} else if pathPrefix != "" {
+		original.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, pathPrefix), "/")
+		original.URL.RawPath = ""
+	}
+
+	if method := r.Header.Get(HeaderOriginalMethod); method != "" {
+		original.Method = strings.ToUpper(method)
+	}
+
+	if host := r.Header.Get(HeaderForwardedHost); host != "" {
+		original.Host = host
+	}
+
+	return original
+}
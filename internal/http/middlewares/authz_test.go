@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorization(t *testing.T) {
+	t.Run("should rebuild the original request of nginx auth_request", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/authz", nil)
+		r.Header.Set("X-Original-URI", "/login?next=/orders")
+		r.Header.Set("X-Original-Method", "post")
+		r.Header.Set("X-Forwarded-Host", "api.example.com")
+
+		original := originalRequest(r, "/authz")
+
+		assert.Equal(t, http.MethodPost, original.Method)
+		assert.Equal(t, "/login", original.URL.Path)
+		assert.Equal(t, "next=/orders", original.URL.RawQuery)
+		assert.Equal(t, "api.example.com", original.Host)
+		assert.Equal(t, "/authz", r.URL.Path)
+	})
+
+	t.Run("should strip the path prefix of envoy ext_authz", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/authz/login", nil)
+
+		original := originalRequest(r, "/authz")
+
+		assert.Equal(t, http.MethodPost, original.Method)
+		assert.Equal(t, "/login", original.URL.Path)
+	})
+
+	t.Run("should allow with 200 and deny with 429 using the forwarded client ip", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+		assert.NoError(t, err)
+
+		login := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, time.Minute), nil)
+		rules, err := NewRuleSet(Rule{Name: "login", Methods: []string{http.MethodPost}, Path: "/login", Config: login})
+		assert.NoError(t, err)
+
+		defaults := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		handler := Authorization(defaults, limiter, "/authz", WithIPResolver(resolver), WithRules(rules))
+
+		check := func(client, path string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/authz"+path, nil)
+			r.RemoteAddr = "10.0.0.2:4711"
+			r.Header.Set("X-Forwarded-For", client)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		allowed := check("198.51.100.1", "/login")
+		assert.Equal(t, http.StatusOK, allowed.Code)
+		assert.Equal(t, "1", allowed.Header().Get("RateLimit-Limit"))
+
+		denied := check("198.51.100.1", "/login")
+		assert.Equal(t, http.StatusTooManyRequests, denied.Code)
+		assert.Equal(t, "60", denied.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusOK, check("198.51.100.2", "/login").Code)
+		assert.Equal(t, http.StatusOK, check("198.51.100.1", "/orders").Code)
+	})
+}
@@ -0,0 +1,176 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	HeaderXForwardedFor  = "X-Forwarded-For"
+	HeaderXRealIP        = "X-Real-Ip"
+	HeaderForwarded      = "Forwarded"
+	HeaderCFConnectingIP = "Cf-Connecting-Ip"
+	HeaderTrueClientIP   = "True-Client-Ip"
+)
+
+var (
+	ErrInvalidTrustedProxy = errors.New("invalid trusted proxy")
+	ErrUnsupportedIPHeader = errors.New("unsupported client ip header")
+	ErrInvalidRemoteAddr   = errors.New("invalid remote address")
+)
+
+type IPResolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+func NewIPResolver(trustedProxies []string, headers []string) (*IPResolver, error) {
+	resolver := &IPResolver{}
+
+	for _, proxy := range trustedProxies {
+		prefix, err := parsePrefix(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTrustedProxy, err)
+		}
+		resolver.trusted = append(resolver.trusted, prefix)
+	}
+
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		switch header {
+		case HeaderXForwardedFor, HeaderXRealIP, HeaderForwarded, HeaderCFConnectingIP, HeaderTrueClientIP:
+			resolver.headers = append(resolver.headers, header)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedIPHeader, header)
+		}
+	}
+
+	if len(resolver.trusted) > 0 && len(resolver.headers) == 0 {
+		resolver.headers = []string{HeaderXForwardedFor}
+	}
+
+	return resolver, nil
+}
+
+func (r *IPResolver) Resolve(req *http.Request) (string, error) {
+	remote, err := parseAddr(req.RemoteAddr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidRemoteAddr, err)
+	}
+
+	if !r.isTrusted(remote) {
+		return remote.String(), nil
+	}
+
+	for _, header := range r.headers {
+		values := req.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		var client netip.Addr
+		var ok bool
+
+		switch header {
+		case HeaderXForwardedFor:
+			client, ok = r.fromChain(splitForwardedFor(values))
+		case HeaderForwarded:
+			client, ok = r.fromChain(splitForwarded(values))
+		default:
+			client, err = parseAddr(strings.TrimSpace(values[len(values)-1]))
+			ok = err == nil
+		}
+
+		if ok {
+			return client.String(), nil
+		}
+	}
+
+	return remote.String(), nil
+}
+
+func (r *IPResolver) fromChain(hops []string) (netip.Addr, bool) {
+	var last netip.Addr
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := parseAddr(hops[i])
+		if err != nil {
+			break
+		}
+		if !r.isTrusted(addr) {
+			return addr, true
+		}
+		last = addr
+	}
+
+	return last, last.IsValid()
+}
+
+func (r *IPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+func splitForwarded(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					hop = strings.Trim(val, `"`)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func parseAddr(value string) (netip.Addr, error) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap().WithZone(""), nil
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPResolver(t *testing.T) {
+	t.Run("should use the remote address and ignore headers by default", func(t *testing.T) {
+		resolver, err := NewIPResolver(nil, nil)
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.7:4711"
+		r.Header.Set("X-Forwarded-For", "1.1.1.1")
+		r.Header.Set("X-Real-IP", "1.1.1.1")
+
+		ip, err := resolver.Resolve(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "203.0.113.7", ip)
+	})
+
+	t.Run("should ignore headers sent by an untrusted peer", func(t *testing.T) {
+		resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.7:4711"
+		r.Header.Set("X-Forwarded-For", "1.1.1.1")
+
+		ip, err := resolver.Resolve(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "203.0.113.7", ip)
+	})
+
+	t.Run("should pick the rightmost untrusted hop of X-Forwarded-For", func(t *testing.T) {
+		resolver, err := NewIPResolver([]string{"10.0.0.0/8", "192.168.1.1"}, []string{"X-Forwarded-For"})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.2:4711"
+		r.Header.Add("X-Forwarded-For", "6.6.6.6, 198.51.100.20")
+		r.Header.Add("X-Forwarded-For", "192.168.1.1, 10.0.0.1")
+
+		ip, err := resolver.Resolve(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "198.51.100.20", ip)
+	})
+
+	t.Run("should stop at a malformed hop and fall back to the remote address", func(t *testing.T) {
+		resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.2:4711"
+		r.Header.Set("X-Forwarded-For", "1.1.1.1, garbage")
+
+		ip, err := resolver.Resolve(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "10.0.0.2", ip)
+	})
+
+	t.Run("should parse the RFC 7239 Forwarded header", func(t *testing.T) {
+		resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, []string{"Forwarded"})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.2:4711"
+		r.Header.Set("Forwarded", `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711";by=10.0.0.2, for=10.0.0.3`)
+
+		ip, err := resolver.Resolve(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "2001:db8:cafe::17", ip)
+	})
+
+	t.Run("should use single value headers in the configured order", func(t *testing.T) {
+		resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, []string{"CF-Connecting-IP", "True-Client-IP", "X-Real-IP"})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.2:4711"
+		r.Header.Set("True-Client-IP", "198.51.100.1")
+		r.Header.Set("X-Real-IP", "198.51.100.2")
+
+		ip, err := resolver.Resolve(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "198.51.100.1", ip)
+	})
+
+	t.Run("should reject invalid trusted proxies and unsupported headers", func(t *testing.T) {
+		_, err := NewIPResolver([]string{"10.0.0.0/33"}, nil)
+		assert.ErrorIs(t, err, ErrInvalidTrustedProxy)
+
+		_, err = NewIPResolver(nil, []string{"X-Client"})
+		assert.ErrorIs(t, err, ErrUnsupportedIPHeader)
+	})
+}
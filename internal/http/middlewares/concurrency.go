@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+)
+
+func ConcurrencyLimiter(next func(w http.ResponseWriter, r *http.Request), limiter ratelimiter.ConcurrencyLimiter, limit int, opts ...Option) http.Handler {
+	o := newOptions(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, err := o.ipResolver.Resolve(r)
+
+		if err != nil {
+			fmt.Printf("error extracting the ip address from the request: %v\n", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		lease, err := limiter.Acquire(r.Context(), ip, limit)
+
+		if err != nil {
+			if errors.Is(err, ratelimiter.ErrMaxConcurrency) {
+				writeResponse(w, http.StatusTooManyRequests, err.Error())
+			} else {
+				writeResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+
+		defer lease.Release(context.Background())
+
+		next(w, r)
+	})
+}
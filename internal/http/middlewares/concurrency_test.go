@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	serve := func(handler http.Handler) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/reports", nil)
+		r.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should reject requests above the in-flight limit", func(t *testing.T) {
+		limiter := ratelimiter.NewInMemoryConcurrencyLimiter()
+
+		var nested *httptest.ResponseRecorder
+		var handler http.Handler
+		handler = ConcurrencyLimiter(func(w http.ResponseWriter, r *http.Request) {
+			if nested == nil {
+				nested = serve(handler)
+			}
+			w.WriteHeader(http.StatusOK)
+		}, limiter, 1)
+
+		assert.Equal(t, http.StatusOK, serve(handler).Code)
+		assert.Equal(t, http.StatusTooManyRequests, nested.Code)
+		assert.Equal(t, http.StatusOK, serve(handler).Code)
+	})
+
+	t.Run("should release the lease when the handler panics", func(t *testing.T) {
+		limiter := ratelimiter.NewInMemoryConcurrencyLimiter()
+
+		handler := ConcurrencyLimiter(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}, limiter, 1)
+
+		assert.Panics(t, func() { serve(handler) })
+		assert.Panics(t, func() { serve(handler) })
+	})
+}
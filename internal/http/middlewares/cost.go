@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+)
+
+type CostFunc func(r *http.Request) int
+
+type costReportKey struct{}
+
+type costReport struct {
+	cost int
+}
+
+func CostByBodySize(bytesPerUnit int64) CostFunc {
+	return func(r *http.Request) int {
+		return weightedCost(1, r, bytesPerUnit)
+	}
+}
+
+func ReportCost(r *http.Request, cost int) {
+	if report, ok := r.Context().Value(costReportKey{}).(*costReport); ok {
+		report.cost = cost
+	}
+}
+
+func withCostReport(r *http.Request) (*http.Request, *costReport) {
+	report := &costReport{}
+	return r.WithContext(context.WithValue(r.Context(), costReportKey{}, report)), report
+}
+
+func (rule *Rule) cost(r *http.Request) int {
+	return weightedCost(rule.Cost, r, rule.CostPerBytes)
+}
+
+func weightedCost(base int, r *http.Request, bytesPerUnit int64) int {
+	if base < 1 {
+		base = 1
+	}
+	if bytesPerUnit <= 0 || r.ContentLength <= 0 {
+		return base
+	}
+	return base + int((r.ContentLength+bytesPerUnit-1)/bytesPerUnit)
+}
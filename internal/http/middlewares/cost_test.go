@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingSleeper struct {
+	calls atomic.Int32
+}
+
+func (s *countingSleeper) Sleep(d time.Duration) {
+	s.calls.Add(1)
+}
+
+func TestCost(t *testing.T) {
+	t.Run("should derive the cost from the body size", func(t *testing.T) {
+		cost := CostByBodySize(1024)
+
+		assert.Equal(t, 1, cost(httptest.NewRequest(http.MethodPost, "/", nil)))
+		assert.Equal(t, 2, cost(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 1024)))))
+		assert.Equal(t, 3, cost(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 1025)))))
+	})
+
+	t.Run("should derive the cost from the matching rule", func(t *testing.T) {
+		rule := &Rule{Cost: 5, CostPerBytes: 100}
+
+		assert.Equal(t, 5, rule.cost(httptest.NewRequest(http.MethodPost, "/", nil)))
+		assert.Equal(t, 8, rule.cost(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 250)))))
+		assert.Equal(t, 1, (&Rule{}).cost(httptest.NewRequest(http.MethodGet, "/", nil)))
+	})
+
+	t.Run("should charge expensive routes more of the quota", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		defaults := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		rules, err := NewRuleSet(Rule{Name: "exports", Path: "/exports", Config: defaults, Cost: 6})
+		assert.NoError(t, err)
+
+		handler := RateLimiterWith(ok, defaults, limiter, WithRules(rules))
+
+		serve := func(target string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			r.RemoteAddr = "127.0.0.1:12345"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		response := serve("/exports")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "4", response.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("/exports").Code)
+	})
+
+	t.Run("should charge the cost given by the cost func", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		handler := RateLimiterWith(ok, config, limiter, WithCost(func(r *http.Request) int {
+			if r.URL.Query().Get("bulk") != "" {
+				return 10
+			}
+			return 0
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/?bulk=1", nil)
+		r.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("should add the cost func on top of the rule cost", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		defaults := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		rules, err := NewRuleSet(Rule{Name: "exports", Path: "/exports", Config: defaults, Cost: 6})
+		assert.NoError(t, err)
+
+		handler := RateLimiterWith(ok, defaults, limiter, WithRules(rules), WithCost(CostByBodySize(1024)))
+
+		r := httptest.NewRequest(http.MethodPost, "/exports", strings.NewReader(strings.Repeat("a", 1024)))
+		r.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("should charge the cost reported by the handler after it runs", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		handler := RateLimiterWith(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/exports" {
+				ReportCost(r, 6)
+			}
+		}, config, limiter)
+
+		serve := func(target string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			r.RemoteAddr = "127.0.0.1:12345"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		response := serve("/exports")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "9", response.Header().Get("RateLimit-Remaining"))
+
+		response = serve("/")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "3", response.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("should not wait for capacity to charge the reported cost", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		sleeper := &countingSleeper{}
+		waiting := ratelimiter.NewWaitingLimiter(limiter, sleeper, time.Minute)
+
+		config := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		handler := RateLimiterWith(func(w http.ResponseWriter, r *http.Request) {
+			ReportCost(r, 20)
+		}, config, waiting)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int32(0), sleeper.calls.Load())
+	})
+
+	t.Run("should ignore a reported cost outside of the middleware", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			ReportCost(httptest.NewRequest(http.MethodGet, "/", nil), 6)
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+)
+
+type HeaderStyle string
+
+const (
+	HeaderStyleIETF   HeaderStyle = "ietf"
+	HeaderStyleLegacy HeaderStyle = "legacy"
+)
+
+func writeRateLimitHeaders(w http.ResponseWriter, decision *ratelimiter.Decision, style HeaderStyle) {
+	if decision == nil || decision.Limit == 0 {
+		return
+	}
+
+	now := time.Now()
+	header := w.Header()
+
+	if style == HeaderStyleLegacy {
+		header.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(decision.Reset.Unix(), 10))
+	} else {
+		header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset.Sub(now))))
+	}
+
+	if decision.QuotaLimit > 0 {
+		header.Set("X-Quota-Limit", strconv.Itoa(decision.QuotaLimit))
+		header.Set("X-Quota-Remaining", strconv.Itoa(decision.QuotaRemaining))
+		if style == HeaderStyleLegacy {
+			header.Set("X-Quota-Reset", strconv.FormatInt(decision.QuotaReset.Unix(), 10))
+		} else {
+			header.Set("X-Quota-Reset", strconv.Itoa(seconds(decision.QuotaReset.Sub(now))))
+		}
+	}
+
+	if !decision.Allowed {
+		header.Set("Retry-After", strconv.Itoa(seconds(decision.RetryAfter)))
+	}
+}
+
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+type options struct {
+	headerStyle HeaderStyle
+	ipResolver  *IPResolver
+	rules       *RuleSet
+	cost        CostFunc
+}
+
+type Option func(*options)
+
+func newOptions(opts []Option) *options {
+	o := &options{headerStyle: HeaderStyleIETF, ipResolver: &IPResolver{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func WithHeaderStyle(style HeaderStyle) Option {
+	return func(o *options) {
+		o.headerStyle = style
+	}
+}
+
+func WithIPResolver(resolver *IPResolver) Option {
+	return func(o *options) {
+		o.ipResolver = resolver
+	}
+}
+
+func WithRules(rules *RuleSet) Option {
+	return func(o *options) {
+		o.rules = rules
+	}
+}
+
+func WithCost(cost CostFunc) Option {
+	return func(o *options) {
+		o.cost = cost
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"net/http"
 
 	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
@@ -15,14 +14,25 @@ type Response struct {
 	Message string `json:"message"`
 }
 
-func RateLimiter(next func(w http.ResponseWriter, r *http.Request), config *ratelimiter.RateLimiterConfig, redisClient *redis.Client) http.Handler {
+func RateLimiter(next func(w http.ResponseWriter, r *http.Request), config *ratelimiter.RateLimiterConfig, redisClient *redis.Client, opts ...Option) http.Handler {
 	rateLimiter := ratelimiter.NewRateLimiter(
 		ratelimiter.NewRedisDatasource(redisClient),
 		ratelimiter.NewTimeSleeper(),
 	)
 
+	return RateLimiterWith(next, config, rateLimiter, opts...)
+}
+
+func RateLimiterWith(next func(w http.ResponseWriter, r *http.Request), config *ratelimiter.RateLimiterConfig, rateLimiter ratelimiter.Limiter, opts ...Option) http.Handler {
+	o := newOptions(opts)
+
+	charger := rateLimiter
+	if waiting, ok := rateLimiter.(*ratelimiter.WaitingLimiter); ok {
+		charger = waiting.Unwrap()
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		ip, err := o.ipResolver.Resolve(r)
 
 		if err != nil {
 			fmt.Printf("error extracting the ip address from the request: %v\n", err)
@@ -30,34 +40,68 @@ func RateLimiter(next func(w http.ResponseWriter, r *http.Request), config *rate
 			return
 		}
 
+		requestConfig := config
+		cost := 1
+		if rule := o.rules.match(r); rule != nil {
+			requestConfig = rule.Config
+			cost = rule.cost(r)
+		}
+
+		if o.cost != nil {
+			if hint := o.cost(r); hint > 0 {
+				cost += hint - 1
+			}
+		}
+
+		if requestConfig == nil {
+			next(w, r)
+			return
+		}
+
 		var token = ""
 
-		if config.ConfigByToken != nil {
-			token = r.Header.Get(config.ConfigByToken.Key)
+		if requestConfig.ConfigByToken != nil {
+			token = r.Header.Get(requestConfig.ConfigByToken.Key)
 		}
 
-		err = rateLimiter.HandleRequest(ip, token, config)
+		decision, err := rateLimiter.HandleRequestN(r.Context(), ip, token, requestConfig, cost)
+
+		writeRateLimitHeaders(w, decision, o.headerStyle)
 
 		if err == nil {
+			r, report := withCostReport(r)
 			next(w, r)
+
+			if extra := report.cost - cost; extra > 0 {
+				if _, err := charger.HandleRequestN(r.Context(), ip, token, requestConfig, extra); err != nil && !errors.Is(err, ratelimiter.ErrMaxRequests) {
+					fmt.Printf("error charging the reported cost of the request: %v\n", err)
+				}
+			}
 		} else {
 			var statusCode int
 			var errMessage string
 
-			if !errors.Is(err, ratelimiter.ErrMaxRequests) {
-				errMessage = "Internal Server Error"
-				statusCode = http.StatusInternalServerError
-			} else {
+			if errors.Is(err, ratelimiter.ErrMaxRequests) {
 				errMessage = err.Error()
 				statusCode = http.StatusTooManyRequests
+			} else if errors.Is(err, ratelimiter.ErrCircuitOpen) {
+				errMessage = http.StatusText(http.StatusServiceUnavailable)
+				statusCode = http.StatusServiceUnavailable
+			} else {
+				errMessage = "Internal Server Error"
+				statusCode = http.StatusInternalServerError
 			}
 
-			response := Response{
-				Message: errMessage,
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(statusCode)
-			json.NewEncoder(w).Encode(&response)
+			writeResponse(w, statusCode, errMessage)
 		}
 	})
 }
+
+func writeResponse(w http.ResponseWriter, statusCode int, message string) {
+	response := Response{
+		Message: message,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(&response)
+}
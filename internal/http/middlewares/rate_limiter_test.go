@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/stretchr/testify/assert"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func newRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "127.0.0.1:12345"
+	return r
+}
+
+func TestRateLimiter(t *testing.T) {
+	config := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, 30*time.Second), nil)
+
+	t.Run("should emit the rate limit headers on allowed and rejected responses", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+		handler := RateLimiterWith(ok, config, limiter)
+
+		allowed := httptest.NewRecorder()
+		handler.ServeHTTP(allowed, newRequest())
+
+		assert.Equal(t, http.StatusOK, allowed.Code)
+		assert.Equal(t, "1", allowed.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", allowed.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "1", allowed.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, allowed.Header().Get("Retry-After"))
+
+		rejected := httptest.NewRecorder()
+		handler.ServeHTTP(rejected, newRequest())
+
+		assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+		assert.Equal(t, "1", rejected.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", rejected.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", rejected.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "30", rejected.Header().Get("Retry-After"))
+	})
+
+	t.Run("should report the remaining quota", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		quota, err := ratelimiter.NewQuotaConfig(100, ratelimiter.QuotaDaily, time.UTC)
+		assert.NoError(t, err)
+
+		ipConfig := ratelimiter.NewRateLimiterConfigByIP(10, time.Second)
+		ipConfig.Quota = quota
+		handler := RateLimiterWith(ok, ratelimiter.NewRateLimiterConfig(ipConfig, nil), limiter)
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, newRequest())
+
+		assert.Equal(t, "100", response.Header().Get("X-Quota-Limit"))
+		assert.Equal(t, "99", response.Header().Get("X-Quota-Remaining"))
+		assert.NotEmpty(t, response.Header().Get("X-Quota-Reset"))
+	})
+
+	t.Run("should emit the legacy header names when configured", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+		handler := RateLimiterWith(ok, config, limiter, WithHeaderStyle(HeaderStyleLegacy))
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, newRequest())
+
+		assert.Equal(t, "1", response.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", response.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, response.Header().Get("X-RateLimit-Reset"))
+		assert.Empty(t, response.Header().Get("RateLimit-Limit"))
+	})
+}
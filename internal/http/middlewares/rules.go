@@ -0,0 +1,244 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+)
+
+var (
+	ErrInvalidRule   = errors.New("invalid rate limit rule")
+	ErrDuplicateRule = errors.New("duplicate rate limit rule")
+)
+
+type Rule struct {
+	Name         string
+	Methods      []string
+	Host         string
+	Path         string
+	Config       *ratelimiter.RateLimiterConfig
+	Cost         int
+	CostPerBytes int64
+}
+
+type RuleSet struct {
+	rules []Rule
+}
+
+func NewRuleSet(rules ...Rule) (*RuleSet, error) {
+	names := make(map[string]bool)
+	set := &RuleSet{}
+
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Config == nil {
+			return nil, fmt.Errorf("%w: a rule needs a name and a config", ErrInvalidRule)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateRule, rule.Name)
+		}
+		names[rule.Name] = true
+
+		for _, pattern := range []string{rule.Host, strings.TrimSuffix(rule.Path, "/**")} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidRule, rule.Name, err)
+			}
+		}
+
+		config := *rule.Config
+		config.Scope = rule.Name
+		rule.Config = &config
+
+		methods := make([]string, 0, len(rule.Methods))
+		for _, method := range rule.Methods {
+			methods = append(methods, strings.ToUpper(method))
+		}
+		rule.Methods = methods
+
+		set.rules = append(set.rules, rule)
+	}
+
+	return set, nil
+}
+
+func (s *RuleSet) Match(r *http.Request) *ratelimiter.RateLimiterConfig {
+	if rule := s.match(r); rule != nil {
+		return rule.Config
+	}
+	return nil
+}
+
+func (s *RuleSet) match(r *http.Request) *Rule {
+	if s == nil {
+		return nil
+	}
+
+	for i := range s.rules {
+		if s.rules[i].matches(r) {
+			return &s.rules[i]
+		}
+	}
+	return nil
+}
+
+func (rule Rule) matches(r *http.Request) bool {
+	if len(rule.Methods) > 0 && !contains(rule.Methods, r.Method) {
+		return false
+	}
+
+	if rule.Host != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if matched, _ := path.Match(strings.ToLower(rule.Host), strings.ToLower(host)); !matched {
+			return false
+		}
+	}
+
+	if rule.Path != "" {
+		if prefix, found := strings.CutSuffix(rule.Path, "/**"); found {
+			return r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/")
+		}
+		if matched, _ := path.Match(rule.Path, r.URL.Path); !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type limitFile struct {
+	Name              string  `json:"name"`
+	Key               string  `json:"key"`
+	RequestsPerSecond int     `json:"requestsPerSecond"`
+	BlockUserFor      string  `json:"blockUserFor"`
+	Window            string  `json:"window"`
+	Burst             int     `json:"burst"`
+	RefillRate        float64 `json:"refillRate"`
+	IPv4Prefix        int     `json:"ipv4Prefix"`
+	IPv6Prefix        int     `json:"ipv6Prefix"`
+}
+
+type ruleFile struct {
+	Name         string      `json:"name"`
+	Methods      []string    `json:"methods"`
+	Host         string      `json:"host"`
+	Path         string      `json:"path"`
+	Strategy     string      `json:"strategy"`
+	IP           *limitFile  `json:"ip"`
+	Token        *limitFile  `json:"token"`
+	Buckets      []limitFile `json:"buckets"`
+	Cost         int         `json:"cost"`
+	CostPerBytes int64       `json:"costPerBytes"`
+}
+
+func LoadRules(filename string) ([]Rule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ruleFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, err
+	}
+
+	rules := make([]Rule, 0, len(files))
+	for _, file := range files {
+		rule, err := file.rule()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidRule, file.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (f ruleFile) rule() (Rule, error) {
+	config := ratelimiter.NewRateLimiterConfig(nil, nil)
+
+	if f.Strategy != "" {
+		strategy, err := ratelimiter.NewStrategy(f.Strategy)
+		if err != nil {
+			return Rule{}, err
+		}
+		config.Strategy = strategy
+	}
+
+	if f.IP != nil {
+		base, err := f.IP.base()
+		if err != nil {
+			return Rule{}, err
+		}
+		config.ConfigByIP = &ratelimiter.RateLimiterConfigByIP{BaseLimiterConfig: base, IPv4Prefix: f.IP.IPv4Prefix, IPv6Prefix: f.IP.IPv6Prefix}
+	}
+
+	if f.Token != nil {
+		base, err := f.Token.base()
+		if err != nil {
+			return Rule{}, err
+		}
+		config.ConfigByToken = &ratelimiter.RateLimiterConfigByToken{BaseLimiterConfig: base, Key: f.Token.Key}
+	}
+
+	for _, bucket := range f.Buckets {
+		if bucket.Name == "" {
+			return Rule{}, errors.New("a bucket needs a name")
+		}
+		base, err := bucket.base()
+		if err != nil {
+			return Rule{}, err
+		}
+		config.Buckets = append(config.Buckets, &ratelimiter.RateLimiterConfigByBucket{BaseLimiterConfig: base, Name: bucket.Name})
+	}
+
+	return Rule{
+		Name:         f.Name,
+		Methods:      f.Methods,
+		Host:         f.Host,
+		Path:         f.Path,
+		Config:       config,
+		Cost:         f.Cost,
+		CostPerBytes: f.CostPerBytes,
+	}, nil
+}
+
+func (f limitFile) base() (ratelimiter.BaseLimiterConfig, error) {
+	base := ratelimiter.BaseLimiterConfig{
+		RequestesPerSecond: f.RequestsPerSecond,
+		Burst:              f.Burst,
+		RefillRate:         f.RefillRate,
+	}
+
+	var err error
+	if f.BlockUserFor != "" {
+		if base.BlockUserFor, err = time.ParseDuration(f.BlockUserFor); err != nil {
+			return base, err
+		}
+	}
+	if f.Window != "" {
+		if base.Window, err = time.ParseDuration(f.Window); err != nil {
+			return base, err
+		}
+	}
+
+	return base, nil
+}
@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joaosczip/go-rate-limiter/pkg/ratelimiter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleSet(t *testing.T) {
+	login := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(5, time.Minute), nil)
+	orders := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(100, time.Second), nil)
+	admin := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, time.Second), nil)
+
+	rules, err := NewRuleSet(
+		Rule{Name: "login", Methods: []string{"post"}, Path: "/login", Config: login},
+		Rule{Name: "orders", Methods: []string{"GET"}, Path: "/orders/**", Config: orders},
+		Rule{Name: "admin", Host: "admin.*", Config: admin},
+	)
+	assert.NoError(t, err)
+
+	t.Run("should select the config of the first matching rule", func(t *testing.T) {
+		config := rules.Match(httptest.NewRequest(http.MethodPost, "/login", nil))
+		assert.Equal(t, "login", config.Scope)
+		assert.Equal(t, 5, config.ConfigByIP.RequestesPerSecond)
+
+		config = rules.Match(httptest.NewRequest(http.MethodGet, "/orders/123/items", nil))
+		assert.Equal(t, "orders", config.Scope)
+
+		config = rules.Match(httptest.NewRequest(http.MethodGet, "http://admin.example.com:8080/login", nil))
+		assert.Equal(t, "admin", config.Scope)
+	})
+
+	t.Run("should not match when the method, path or host differ", func(t *testing.T) {
+		assert.Nil(t, rules.Match(httptest.NewRequest(http.MethodGet, "/login", nil)))
+		assert.Nil(t, rules.Match(httptest.NewRequest(http.MethodGet, "/ordersx", nil)))
+		assert.Nil(t, rules.Match(httptest.NewRequest(http.MethodPost, "/orders/1", nil)))
+	})
+
+	t.Run("should not change the configs given to the rules", func(t *testing.T) {
+		assert.Empty(t, login.Scope)
+	})
+
+	t.Run("should reject invalid and duplicated rules", func(t *testing.T) {
+		_, err := NewRuleSet(Rule{Name: "login", Config: login}, Rule{Name: "login", Config: orders})
+		assert.ErrorIs(t, err, ErrDuplicateRule)
+
+		_, err = NewRuleSet(Rule{Name: "broken", Path: "/[", Config: login})
+		assert.ErrorIs(t, err, ErrInvalidRule)
+
+		_, err = NewRuleSet(Rule{Name: "empty"})
+		assert.ErrorIs(t, err, ErrInvalidRule)
+	})
+
+	t.Run("should limit each rule with its own config and quota", func(t *testing.T) {
+		limiter := ratelimiter.NewRateLimiter(ratelimiter.NewInMemoryDatasource(), ratelimiter.NewTimeSleeper())
+		defer limiter.Stop()
+
+		strict := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(1, time.Minute), nil)
+		rules, err := NewRuleSet(Rule{Name: "login", Methods: []string{http.MethodPost}, Path: "/login", Config: strict})
+		assert.NoError(t, err)
+
+		defaults := ratelimiter.NewRateLimiterConfig(ratelimiter.NewRateLimiterConfigByIP(10, time.Second), nil)
+		handler := RateLimiterWith(ok, defaults, limiter, WithRules(rules))
+
+		serve := func(method, target string) int {
+			r := httptest.NewRequest(method, target, nil)
+			r.RemoteAddr = "127.0.0.1:12345"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/login"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/login"))
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/orders"))
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/login"))
+	})
+
+	t.Run("should load the rules from a file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "rules.json")
+		assert.NoError(t, os.WriteFile(filename, []byte(`[
+			{
+				"name": "login",
+				"methods": ["POST"],
+				"path": "/login",
+				"strategy": "sliding-window-log",
+				"ip": {"requestsPerSecond": 5, "blockUserFor": "5m", "window": "1m", "ipv4Prefix": 24, "ipv6Prefix": 64},
+				"token": {"key": "API_KEY", "requestsPerSecond": 10, "blockUserFor": "1m"},
+				"buckets": [{"name": "database", "requestsPerSecond": 100}]
+			}
+		]`), 0o600))
+
+		loaded, err := LoadRules(filename)
+		assert.NoError(t, err)
+		assert.Len(t, loaded, 1)
+
+		rule := loaded[0]
+		assert.Equal(t, "/login", rule.Path)
+		assert.Equal(t, []string{"POST"}, rule.Methods)
+		assert.Equal(t, ratelimiter.StrategySlidingWindowLog, rule.Config.Strategy.Name())
+		assert.Equal(t, 5, rule.Config.ConfigByIP.RequestesPerSecond)
+		assert.Equal(t, 5*time.Minute, rule.Config.ConfigByIP.BlockUserFor)
+		assert.Equal(t, time.Minute, rule.Config.ConfigByIP.Window)
+		assert.Equal(t, 24, rule.Config.ConfigByIP.IPv4Prefix)
+		assert.Equal(t, 64, rule.Config.ConfigByIP.IPv6Prefix)
+		assert.Equal(t, "API_KEY", rule.Config.ConfigByToken.Key)
+		assert.Equal(t, []*ratelimiter.RateLimiterConfigByBucket{ratelimiter.NewRateLimiterConfigByBucket(100, 0, "database")}, rule.Config.Buckets)
+	})
+
+	t.Run("should reject rule files with invalid limits", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "rules.json")
+		assert.NoError(t, os.WriteFile(filename, []byte(`[{"name": "login", "ip": {"blockUserFor": "soon"}}]`), 0o600))
+
+		_, err := LoadRules(filename)
+		assert.ErrorIs(t, err, ErrInvalidRule)
+	})
+}
@@ -0,0 +1,26 @@
+package ratelimiter
+
+import "net/netip"
+
+func (c *RateLimiterConfigByIP) aggregate(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap().WithZone("")
+
+	bits := c.IPv4Prefix
+	if addr.Is6() {
+		bits = c.IPv6Prefix
+	}
+
+	if bits <= 0 || bits >= addr.BitLen() {
+		return addr.String()
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
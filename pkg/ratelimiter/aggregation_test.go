@@ -0,0 +1,55 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPAggregation(t *testing.T) {
+	t.Run("should key on the exact address when no prefix is configured", func(t *testing.T) {
+		config := NewRateLimiterConfigByIP(10, time.Second)
+
+		assert.Equal(t, "2001:db8::1", config.aggregate("2001:db8::1"))
+		assert.Equal(t, "203.0.113.7", config.aggregate("203.0.113.7"))
+	})
+
+	t.Run("should mask the address with the prefix of its family", func(t *testing.T) {
+		config := NewRateLimiterConfigByIP(10, time.Second)
+		config.IPv4Prefix = 24
+		config.IPv6Prefix = 64
+
+		assert.Equal(t, "2001:db8:0:1::/64", config.aggregate("2001:db8:0:1:aaaa:bbbb:cccc:dddd"))
+		assert.Equal(t, "203.0.113.0/24", config.aggregate("203.0.113.7"))
+		assert.Equal(t, "203.0.113.0/24", config.aggregate("::ffff:203.0.113.7"))
+		assert.Equal(t, "not-an-ip", config.aggregate("not-an-ip"))
+	})
+
+	t.Run("should share the quota across addresses of the same prefix", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		ipConfig := NewRateLimiterConfigByIP(2, 10*time.Second)
+		ipConfig.IPv6Prefix = 64
+		config := NewRateLimiterConfig(ipConfig, nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("2001:db8::1", "", config)))
+		assert.NoError(t, requestErr(limiter.HandleRequest("2001:db8::2", "", config)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("2001:db8::3", "", config)), ErrMaxRequests)
+		assert.NoError(t, requestErr(limiter.HandleRequest("2001:db8:0:1::1", "", config)))
+	})
+
+	t.Run("should share the quota across addresses of the same prefix in redis", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		ipConfig := NewRateLimiterConfigByIP(1, 10*time.Second)
+		ipConfig.IPv6Prefix = 64
+		config := NewRateLimiterConfig(ipConfig, nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("2001:db8::1", "", config)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("2001:db8::2", "", config)), ErrMaxRequests)
+		assert.True(t, server.Exists("ratelimiter:ip:2001:db8::/64"))
+	})
+}
@@ -0,0 +1,170 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownCombinationMode = errors.New("unknown combination mode")
+
+type CombinationMode string
+
+const (
+	CombineTokenOverridesIP CombinationMode = "token-overrides-ip"
+	CombineAll              CombinationMode = "all"
+	CombineMostPermissive   CombinationMode = "most-permissive"
+)
+
+func ParseCombinationMode(mode string) (CombinationMode, error) {
+	switch CombinationMode(mode) {
+	case "", CombineTokenOverridesIP:
+		return CombineTokenOverridesIP, nil
+	case CombineAll, CombineMostPermissive:
+		return CombinationMode(mode), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownCombinationMode, mode)
+}
+
+type dimensionLimit struct {
+	dimension Dimension
+	value     string
+	config    *BaseLimiterConfig
+}
+
+func (c *RateLimiterConfig) mode() CombinationMode {
+	if c.Mode == "" {
+		return CombineTokenOverridesIP
+	}
+	return c.Mode
+}
+
+func (c *RateLimiterConfig) limits(ctx context.Context, ip, token string) ([]dimensionLimit, error) {
+	if c.mode() == CombineTokenOverridesIP {
+		dimension, value, config, err := c.limitFor(ctx, ip, token)
+		if errors.Is(err, ErrNilConfig) && c.hasSharedLimits() {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []dimensionLimit{{dimension, value, config}}, nil
+	}
+
+	var limits []dimensionLimit
+
+	if c.ConfigByIP != nil {
+		limits = append(limits, dimensionLimit{DimensionIP, c.ConfigByIP.aggregate(ip), &c.ConfigByIP.BaseLimiterConfig})
+	}
+
+	if token != "" && c.ConfigByToken != nil {
+		config, err := c.ConfigByToken.limitsFor(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, dimensionLimit{DimensionToken, token, config})
+	}
+
+	if len(limits) == 0 && !c.hasSharedLimits() {
+		return nil, ErrNilConfig
+	}
+	return limits, nil
+}
+
+type evaluation func(key string, limit dimensionLimit, charge bool) (*Decision, error)
+
+func (e evaluation) peeking() evaluation {
+	return func(key string, limit dimensionLimit, _ bool) (*Decision, error) {
+		return e(key, limit, false)
+	}
+}
+
+type keyedLimit struct {
+	key   string
+	limit dimensionLimit
+}
+
+func keyed(keyspace Keyspace, limits []dimensionLimit) []keyedLimit {
+	keyedLimits := make([]keyedLimit, 0, len(limits))
+	for _, limit := range limits {
+		keyedLimits = append(keyedLimits, keyedLimit{keyspace.Key(limit.dimension, limit.value), limit})
+	}
+	return keyedLimits
+}
+
+func enforce(keyspace Keyspace, config *RateLimiterConfig, limits []dimensionLimit, evaluate evaluation) (*Decision, error) {
+	checks := keyed(keyspace, config.sharedLimits())
+	client := keyed(keyspace.Scoped(config.Scope), limits)
+
+	if config.mode() == CombineMostPermissive && len(client) > 1 {
+		selected, decision, err := mostPermissive(client, evaluate)
+		if selected == nil {
+			return decision, err
+		}
+		client = []keyedLimit{*selected}
+	}
+
+	return all(append(checks, client...), evaluate)
+}
+
+func all(limits []keyedLimit, evaluate evaluation) (*Decision, error) {
+	if len(limits) > 1 {
+		for _, limit := range limits {
+			decision, err := evaluate(limit.key, limit.limit, false)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, ErrMaxRequests) {
+				return nil, err
+			}
+			if charged, chargeErr := evaluate(limit.key, limit.limit, true); chargeErr != nil {
+				return charged, chargeErr
+			}
+			return decision, err
+		}
+	}
+
+	var result *Decision
+
+	for _, limit := range limits {
+		decision, err := evaluate(limit.key, limit.limit, true)
+		if err != nil {
+			return decision, err
+		}
+		if result == nil || decision.Remaining < result.Remaining {
+			result = decision
+		}
+	}
+
+	return result, nil
+}
+
+func mostPermissive(limits []keyedLimit, evaluate evaluation) (*keyedLimit, *Decision, error) {
+	for i, limit := range limits {
+		decision, err := evaluate(limit.key, limit.limit, false)
+		if err != nil && !errors.Is(err, ErrMaxRequests) {
+			return nil, nil, err
+		}
+		if decision.Allowed {
+			return &limits[i], decision, nil
+		}
+	}
+
+	var result *Decision
+	var denied error
+
+	for _, limit := range limits {
+		decision, err := evaluate(limit.key, limit.limit, true)
+		if err == nil {
+			return nil, decision, nil
+		}
+		if !errors.Is(err, ErrMaxRequests) {
+			return nil, nil, err
+		}
+		if result == nil || decision.RetryAfter < result.RetryAfter {
+			result, denied = decision, err
+		}
+	}
+
+	return nil, result, denied
+}
@@ -0,0 +1,105 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCompositeConfig(mode CombinationMode) *RateLimiterConfig {
+	config := NewRateLimiterConfig(
+		NewRateLimiterConfigByIP(3, 10*time.Second),
+		NewRateLimiterConfigByToken(5, 10*time.Second, "API_KEY"),
+	)
+	config.Mode = mode
+	return config
+}
+
+func TestCompositeLimits(t *testing.T) {
+	t.Run("should parse the combination modes", func(t *testing.T) {
+		mode, err := ParseCombinationMode("")
+		assert.NoError(t, err)
+		assert.Equal(t, CombineTokenOverridesIP, mode)
+
+		mode, err = ParseCombinationMode("all")
+		assert.NoError(t, err)
+		assert.Equal(t, CombineAll, mode)
+
+		_, err = ParseCombinationMode("any")
+		assert.ErrorIs(t, err, ErrUnknownCombinationMode)
+	})
+
+	for name, newLimiter := range testLimiters() {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should only enforce the token limit when the token overrides the ip", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := newCompositeConfig(CombineTokenOverridesIP)
+
+				for i := 0; i < 5; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc1234", config)))
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc1234", config)), ErrMaxRequests)
+			})
+
+			t.Run("should require every dimension to pass", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := newCompositeConfig(CombineAll)
+
+				for i := 0; i < 3; i++ {
+					decision, err := limiter.HandleRequest("127.0.0.1", "abc1234", config)
+					assert.NoError(t, err)
+					assert.Equal(t, 3, decision.Limit)
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc1234", config)), ErrMaxRequests)
+
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "abc1234", config)))
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.2", "abc1234", config)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.3", "abc1234", config)), ErrMaxRequests)
+			})
+
+			t.Run("should enforce the ip limit alone when no token is sent", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := newCompositeConfig(CombineAll)
+
+				for i := 0; i < 3; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should not charge the other dimensions when one of them denies", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(
+					NewRateLimiterConfigByIP(10, 0),
+					NewRateLimiterConfigByToken(2, 0, "API_KEY"),
+				)
+				config.ConfigByIP.Window = time.Hour
+				config.ConfigByToken.Window = time.Hour
+				config.Mode = CombineAll
+
+				for i := 0; i < 8; i++ {
+					limiter.HandleRequest("127.0.0.1", "abc1234", config)
+				}
+
+				for i := 0; i < 8; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should allow the request while any dimension passes", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := newCompositeConfig(CombineMostPermissive)
+
+				for i := 0; i < 8; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc1234", config)))
+				}
+
+				decision, err := limiter.HandleRequest("127.0.0.1", "abc1234", config)
+				assert.ErrorIs(t, err, ErrMaxRequests)
+				assert.False(t, decision.Allowed)
+			})
+		})
+	}
+}
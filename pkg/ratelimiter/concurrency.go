@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrMaxConcurrency = errors.New("you have reached the maximum number of concurrent requests")
+
+type ConcurrencyLimiter interface {
+	Acquire(ctx context.Context, key string, limit int) (*Lease, error)
+}
+
+type Lease struct {
+	once    sync.Once
+	release func(ctx context.Context) error
+	err     error
+}
+
+func newLease(release func(ctx context.Context) error) *Lease {
+	return &Lease{release: release}
+}
+
+func (l *Lease) Release(ctx context.Context) error {
+	l.once.Do(func() {
+		l.err = l.release(ctx)
+	})
+	return l.err
+}
+
+type InMemoryConcurrencyLimiter struct {
+	keyspace Keyspace
+	inFlight map[string]int
+	mux      sync.Mutex
+}
+
+func NewInMemoryConcurrencyLimiter(opts ...Option) *InMemoryConcurrencyLimiter {
+	o := newOptions(opts)
+	return &InMemoryConcurrencyLimiter{keyspace: o.keyspace, inFlight: make(map[string]int)}
+}
+
+func (l *InMemoryConcurrencyLimiter) Acquire(ctx context.Context, key string, limit int) (*Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key = l.keyspace.Key(DimensionConcurrency, key)
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.inFlight[key] >= limit {
+		return nil, ErrMaxConcurrency
+	}
+	l.inFlight[key]++
+
+	return newLease(func(ctx context.Context) error {
+		l.mux.Lock()
+		defer l.mux.Unlock()
+
+		if l.inFlight[key]--; l.inFlight[key] <= 0 {
+			delete(l.inFlight, key)
+		}
+		return nil
+	}), nil
+}
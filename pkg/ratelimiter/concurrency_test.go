@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	for name, newLimiter := range map[string]func(t *testing.T) ConcurrencyLimiter{
+		"in memory": func(t *testing.T) ConcurrencyLimiter {
+			return NewInMemoryConcurrencyLimiter()
+		},
+		"redis": func(t *testing.T) ConcurrencyLimiter {
+			_, client := newTestRedis(t)
+			return NewRedisConcurrencyLimiter(client, time.Minute)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should cap the in-flight requests per key", func(t *testing.T) {
+				limiter := newLimiter(t)
+				ctx := context.Background()
+
+				first, err := limiter.Acquire(ctx, "127.0.0.1", 2)
+				assert.NoError(t, err)
+				second, err := limiter.Acquire(ctx, "127.0.0.1", 2)
+				assert.NoError(t, err)
+
+				_, err = limiter.Acquire(ctx, "127.0.0.1", 2)
+				assert.ErrorIs(t, err, ErrMaxConcurrency)
+
+				other, err := limiter.Acquire(ctx, "10.0.0.1", 2)
+				assert.NoError(t, err)
+
+				assert.NoError(t, first.Release(ctx))
+				third, err := limiter.Acquire(ctx, "127.0.0.1", 2)
+				assert.NoError(t, err)
+
+				for _, lease := range []*Lease{second, third, other} {
+					assert.NoError(t, lease.Release(ctx))
+				}
+			})
+
+			t.Run("should release a lease only once", func(t *testing.T) {
+				limiter := newLimiter(t)
+				ctx := context.Background()
+
+				lease, err := limiter.Acquire(ctx, "127.0.0.1", 1)
+				assert.NoError(t, err)
+				assert.NoError(t, lease.Release(ctx))
+				assert.NoError(t, lease.Release(ctx))
+
+				held, err := limiter.Acquire(ctx, "127.0.0.1", 1)
+				assert.NoError(t, err)
+				defer held.Release(ctx)
+
+				_, err = limiter.Acquire(ctx, "127.0.0.1", 1)
+				assert.ErrorIs(t, err, ErrMaxConcurrency)
+			})
+
+			t.Run("should not over-admit concurrent acquisitions", func(t *testing.T) {
+				limiter := newLimiter(t)
+
+				var mux sync.Mutex
+				var wg sync.WaitGroup
+				acquired := 0
+
+				for i := 0; i < 30; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if _, err := limiter.Acquire(context.Background(), "127.0.0.1", 5); err == nil {
+							mux.Lock()
+							acquired++
+							mux.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+
+				assert.Equal(t, 5, acquired)
+			})
+		})
+	}
+
+	t.Run("should expire the leases of a dead instance", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisConcurrencyLimiter(client, 10*time.Second)
+
+		_, err := limiter.Acquire(context.Background(), "127.0.0.1", 1)
+		assert.NoError(t, err)
+		_, err = limiter.Acquire(context.Background(), "127.0.0.1", 1)
+		assert.ErrorIs(t, err, ErrMaxConcurrency)
+
+		server.SetTime(time.Date(2024, 1, 1, 0, 0, 11, 0, time.UTC))
+
+		lease, err := limiter.Acquire(context.Background(), "127.0.0.1", 1)
+		assert.NoError(t, err)
+		assert.NoError(t, lease.Release(context.Background()))
+	})
+
+	t.Run("should namespace the lease keys", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisConcurrencyLimiter(client, time.Minute, WithKeyPrefix("reports"))
+
+		lease, err := limiter.Acquire(context.Background(), "127.0.0.1", 1)
+		assert.NoError(t, err)
+		defer lease.Release(context.Background())
+
+		assert.True(t, server.Exists("reports:concurrency:127.0.0.1"))
+	})
+}
@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+type ContextDatasource interface {
+	SetContext(ctx context.Context, key string, data *ClientRateLimiter) error
+	GetContext(ctx context.Context, key string) (*ClientRateLimiter, error)
+	HasContext(ctx context.Context, key string) (bool, error)
+}
+
+type contextDatasource struct {
+	datasource Datasource
+	timeout    time.Duration
+}
+
+func withContext(datasource Datasource, timeout time.Duration) *contextDatasource {
+	return &contextDatasource{datasource: datasource, timeout: timeout}
+}
+
+func (d *contextDatasource) SetContext(ctx context.Context, key string, data *ClientRateLimiter) error {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	if datasource, ok := d.datasource.(ContextDatasource); ok {
+		return datasource.SetContext(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.datasource.Set(key, data)
+}
+
+func (d *contextDatasource) GetContext(ctx context.Context, key string) (*ClientRateLimiter, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	if datasource, ok := d.datasource.(ContextDatasource); ok {
+		return datasource.GetContext(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.datasource.Get(key)
+}
+
+func (d *contextDatasource) HasContext(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	if datasource, ok := d.datasource.(ContextDatasource); ok {
+		return datasource.HasContext(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return d.datasource.Has(key), nil
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
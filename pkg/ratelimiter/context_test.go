@@ -0,0 +1,84 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type slowDatasource struct {
+	*InMemoryDatasource
+}
+
+func (d *slowDatasource) SetContext(ctx context.Context, key string, data *ClientRateLimiter) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (d *slowDatasource) GetContext(ctx context.Context, key string) (*ClientRateLimiter, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (d *slowDatasource) HasContext(ctx context.Context, key string) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func TestHandleRequestContext(t *testing.T) {
+	config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+
+	t.Run("should not query the datasource when the context is canceled", func(t *testing.T) {
+		datasource := &DatasourceMock{}
+		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := limiter.HandleRequestContext(ctx, "127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, ErrGettingRateLimiterData)
+		assert.ErrorIs(t, err, context.Canceled)
+		datasource.AssertNotCalled(t, "Has", "ratelimiter:ip:127.0.0.1")
+	})
+
+	t.Run("should abort a slow datasource call after the configured timeout", func(t *testing.T) {
+		limiter := NewRateLimiter(&slowDatasource{NewInMemoryDatasource()}, NewTimeSleeper(), WithTimeout(10*time.Millisecond))
+		defer limiter.Stop()
+
+		start := time.Now()
+		_, err := limiter.HandleRequestContext(context.Background(), "127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, ErrGettingRateLimiterData)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should propagate the request deadline to the datasource", func(t *testing.T) {
+		limiter := NewRateLimiter(&slowDatasource{NewInMemoryDatasource()}, NewTimeSleeper())
+		defer limiter.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := limiter.HandleRequestContext(ctx, "127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should propagate the context to the redis limiter", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := limiter.HandleRequestContext(ctx, "127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, ErrGettingRateLimiterData)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,74 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedRequests(t *testing.T) {
+	for name, newLimiter := range testLimiters() {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should consume the cost of the request from the quota", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+
+				decision, err := limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 4)
+				assert.NoError(t, err)
+				assert.Equal(t, 6, decision.Remaining)
+
+				assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 6)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should deny a request costing more than the remaining quota", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+
+				assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 8)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 3)), ErrMaxRequests)
+			})
+
+			t.Run("should restore the charged cost once the window passes", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 0), nil)
+
+				assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 10)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+
+				limiter.Advance(time.Second)
+
+				assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 10)))
+			})
+
+			t.Run("should charge at least one request", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 10*time.Second), nil)
+
+				assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 0)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 0)), ErrMaxRequests)
+			})
+		})
+	}
+
+	t.Run("should charge the cost to every combined dimension", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+		config.ConfigGlobal = NewRateLimiterConfigGlobal(5, 0)
+
+		assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "10.0.0.1", "", config, 3)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequestN(context.Background(), "10.0.0.2", "", config, 3)), ErrMaxRequests)
+	})
+
+	t.Run("should pass the cost to the local fallback", func(t *testing.T) {
+		limiter := NewResilientLimiter(&failingLimiter{err: ErrGettingRateLimiterData}, NewCircuitBreaker(5, time.Minute), FailLocal)
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(5, 10*time.Second), nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequestN(context.Background(), "127.0.0.1", "", config, 5)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+	})
+}
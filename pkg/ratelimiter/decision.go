@@ -0,0 +1,42 @@
+package ratelimiter
+
+import "time"
+
+type Decision struct {
+	Allowed        bool
+	Limit          int
+	Remaining      int
+	Reset          time.Time
+	RetryAfter     time.Duration
+	QuotaLimit     int
+	QuotaRemaining int
+	QuotaReset     time.Time
+}
+
+func newDecision(client *ClientRateLimiter, strategy Strategy, now time.Time, allowed bool) *Decision {
+	limit, remaining, reset := strategy.Status(client, now)
+
+	decision := &Decision{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+	client.quotaStatus(decision, now)
+
+	if allowed {
+		return decision
+	}
+
+	decision.Remaining = 0
+	if client.isBlocked() {
+		if blockedUntil := client.BlockedAt.Add(client.BlockUserFor); blockedUntil.After(decision.Reset) {
+			decision.Reset = blockedUntil
+		}
+	}
+	if retryAfter := decision.Reset.Sub(now); retryAfter > 0 {
+		decision.RetryAfter = retryAfter
+	}
+
+	return decision
+}
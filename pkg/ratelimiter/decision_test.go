@@ -0,0 +1,95 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func requestErr(_ *Decision, err error) error {
+	return err
+}
+
+func TestDecision(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should report the limit, remaining quota and reset of an allowed request", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(newTestClock(start).Now))
+		defer limiter.Stop()
+
+		configByIP := NewRateLimiterConfigByIP(10, 30*time.Second)
+		configByIP.Window = time.Minute
+		config := NewRateLimiterConfig(configByIP, nil)
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+
+		assert.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 10, decision.Limit)
+		assert.Equal(t, 9, decision.Remaining)
+		assert.Equal(t, start.Add(time.Minute), decision.Reset)
+		assert.Zero(t, decision.RetryAfter)
+	})
+
+	t.Run("should ask the user to retry after the blocking period", func(t *testing.T) {
+		clock := newTestClock(start)
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(clock.Now))
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 30*time.Second), nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, 0, decision.Remaining)
+		assert.Equal(t, 30*time.Second, decision.RetryAfter)
+
+		clock.Advance(10 * time.Second)
+		decision, err = limiter.HandleRequest("127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.Equal(t, 20*time.Second, decision.RetryAfter)
+	})
+
+	t.Run("should report when the next token is available", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(newTestClock(start).Now))
+		defer limiter.Stop()
+
+		configByIP := NewRateLimiterConfigByIP(1, 0)
+		configByIP.Burst = 2
+		configByIP.RefillRate = 0.5
+		config := NewRateLimiterConfig(configByIP, nil)
+		config.Strategy = NewTokenBucket()
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, decision.Limit)
+		assert.Equal(t, 1, decision.Remaining)
+		assert.Equal(t, start.Add(2*time.Second), decision.Reset)
+	})
+
+	t.Run("should report the same decision from the redis limiter", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, 30*time.Second), nil)
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+		assert.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 2, decision.Limit)
+		assert.Equal(t, 1, decision.Remaining)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		decision, err = limiter.HandleRequest("127.0.0.1", "", config)
+
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, 0, decision.Remaining)
+		assert.Equal(t, 30*time.Second, decision.RetryAfter)
+	})
+}
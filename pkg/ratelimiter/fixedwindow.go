@@ -0,0 +1,33 @@
+package ratelimiter
+
+import "time"
+
+type FixedWindow struct{}
+
+func NewFixedWindow() *FixedWindow {
+	return &FixedWindow{}
+}
+
+func (s *FixedWindow) Name() string {
+	return StrategyFixedWindow
+}
+
+func (s *FixedWindow) AllowN(client *ClientRateLimiter, now time.Time, n int) bool {
+	if client.hasWindowExpired(now) {
+		client.WindowStart = now
+		client.TotalRequests = 0
+	}
+
+	if client.TotalRequests+n > client.RequestsPerSecond {
+		return false
+	}
+	client.TotalRequests += n
+	return true
+}
+
+func (s *FixedWindow) Status(client *ClientRateLimiter, now time.Time) (int, int, time.Time) {
+	if client.hasWindowExpired(now) {
+		return client.RequestsPerSecond, client.RequestsPerSecond, now
+	}
+	return client.RequestsPerSecond, remaining(client.RequestsPerSecond, float64(client.TotalRequests)), client.WindowStart.Add(client.window())
+}
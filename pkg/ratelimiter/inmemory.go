@@ -21,6 +21,8 @@ func (d *InMemoryDatasource) Set(key string, data *ClientRateLimiter) error {
 }
 
 func (d *InMemoryDatasource) Get(key string) (*ClientRateLimiter, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
 	if data, found := d.clients[key]; found {
 		return data, nil
 	}
@@ -28,10 +30,25 @@ func (d *InMemoryDatasource) Get(key string) (*ClientRateLimiter, error) {
 }
 
 func (d *InMemoryDatasource) Has(key string) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
 	_, found := d.clients[key]
 	return found
 }
 
 func (d *InMemoryDatasource) All() (map[string]*ClientRateLimiter, error) {
-	return d.clients, nil
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	clients := make(map[string]*ClientRateLimiter, len(d.clients))
+	for key, client := range d.clients {
+		clients[key] = client
+	}
+	return clients, nil
+}
+
+func (d *InMemoryDatasource) Delete(key string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.clients, key)
+	return nil
 }
@@ -0,0 +1,40 @@
+package ratelimiter
+
+import "strings"
+
+const DefaultKeyPrefix = "ratelimiter"
+
+type Dimension string
+
+const (
+	DimensionIP          Dimension = "ip"
+	DimensionToken       Dimension = "token"
+	DimensionRoute       Dimension = "route"
+	DimensionGlobal      Dimension = "global"
+	DimensionBucket      Dimension = "bucket"
+	DimensionConcurrency Dimension = "concurrency"
+	DimensionLog         Dimension = "log"
+)
+
+type Keyspace struct {
+	Prefix string
+}
+
+func NewKeyspace(prefix string) Keyspace {
+	return Keyspace{Prefix: prefix}
+}
+
+func (k Keyspace) Key(dimension Dimension, value string) string {
+	parts := []string{string(dimension), value}
+	if k.Prefix != "" {
+		parts = append([]string{k.Prefix}, parts...)
+	}
+	return strings.Join(parts, ":")
+}
+
+func (k Keyspace) Scoped(scope string) Keyspace {
+	if scope == "" {
+		return k
+	}
+	return NewKeyspace(k.Key(DimensionRoute, scope))
+}
@@ -0,0 +1,81 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspace(t *testing.T) {
+	t.Run("should namespace the keys by prefix and dimension", func(t *testing.T) {
+		assert.Equal(t, "ratelimiter:ip:127.0.0.1", NewKeyspace(DefaultKeyPrefix).Key(DimensionIP, "127.0.0.1"))
+		assert.Equal(t, "orders:token:abc1234", NewKeyspace("orders").Key(DimensionToken, "abc1234"))
+		assert.Equal(t, "route:/login", NewKeyspace("").Key(DimensionRoute, "/login"))
+	})
+
+	t.Run("should scope the keys by route", func(t *testing.T) {
+		assert.Equal(t, "ratelimiter:route:login:ip:127.0.0.1", NewKeyspace(DefaultKeyPrefix).Scoped("login").Key(DimensionIP, "127.0.0.1"))
+		assert.Equal(t, "ratelimiter:ip:127.0.0.1", NewKeyspace(DefaultKeyPrefix).Scoped("").Key(DimensionIP, "127.0.0.1"))
+	})
+
+	t.Run("should keep separate quotas for configs with different scopes", func(t *testing.T) {
+		datasource := NewInMemoryDatasource()
+		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
+
+		login := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 10*time.Second), nil)
+		login.Scope = "login"
+		orders := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 10*time.Second), nil)
+		orders.Scope = "orders"
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", login)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", login)), ErrMaxRequests)
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", orders)))
+		assert.True(t, datasource.Has("ratelimiter:route:login:ip:127.0.0.1"))
+	})
+
+	t.Run("should not mix an ip and a token with the same value", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(
+			NewRateLimiterConfigByIP(1, 10*time.Second),
+			NewRateLimiterConfigByToken(1, 10*time.Second, "API_KEY"),
+		)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("same-value", "", config)))
+		assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "same-value", config)))
+	})
+
+	t.Run("should isolate limiters with different prefixes sharing a datasource", func(t *testing.T) {
+		datasource := NewInMemoryDatasource()
+		orders := NewRateLimiter(datasource, NewTimeSleeper(), WithKeyPrefix("orders"))
+		defer orders.Stop()
+		payments := NewRateLimiter(datasource, NewTimeSleeper(), WithKeyPrefix("payments"))
+		defer payments.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 10*time.Second), nil)
+
+		assert.NoError(t, requestErr(orders.HandleRequest("127.0.0.1", "", config)))
+		assert.NoError(t, requestErr(payments.HandleRequest("127.0.0.1", "", config)))
+		assert.True(t, datasource.Has("orders:ip:127.0.0.1"))
+		assert.True(t, datasource.Has("payments:ip:127.0.0.1"))
+	})
+
+	t.Run("should namespace the keys of the redis limiter", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client, WithKeyPrefix("orders"))
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 10*time.Second), nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		assert.True(t, server.Exists("orders:ip:127.0.0.1"))
+
+		scoped := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 10*time.Second), nil)
+		scoped.Scope = "login"
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", scoped)))
+		assert.True(t, server.Exists("orders:route:login:ip:127.0.0.1"))
+	})
+}
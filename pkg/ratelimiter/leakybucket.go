@@ -0,0 +1,53 @@
+package ratelimiter
+
+import (
+	"math"
+	"time"
+)
+
+type LeakyBucket struct{}
+
+func NewLeakyBucket() *LeakyBucket {
+	return &LeakyBucket{}
+}
+
+func (s *LeakyBucket) Name() string {
+	return StrategyLeakyBucket
+}
+
+func (s *LeakyBucket) AllowN(client *ClientRateLimiter, now time.Time, n int) bool {
+	s.leak(client, now)
+
+	if client.Level+float64(n) > float64(client.RequestsPerSecond) {
+		return false
+	}
+
+	client.Level += float64(n)
+	return true
+}
+
+func (s *LeakyBucket) Status(client *ClientRateLimiter, now time.Time) (int, int, time.Time) {
+	s.leak(client, now)
+
+	capacity := client.RequestsPerSecond
+	free := remaining(capacity, client.Level)
+	if free >= capacity {
+		return capacity, capacity, now
+	}
+
+	overflow := client.Level - float64(capacity-free-1)
+	return capacity, free, now.Add(time.Duration(overflow / s.leakRate(client) * float64(time.Second)))
+}
+
+func (s *LeakyBucket) leak(client *ClientRateLimiter, now time.Time) {
+	if !client.LastLeak.IsZero() {
+		if elapsed := now.Sub(client.LastLeak); elapsed > 0 {
+			client.Level = math.Max(0, client.Level-elapsed.Seconds()*s.leakRate(client))
+		}
+	}
+	client.LastLeak = now
+}
+
+func (s *LeakyBucket) leakRate(client *ClientRateLimiter) float64 {
+	return float64(client.RequestsPerSecond) / client.window().Seconds()
+}
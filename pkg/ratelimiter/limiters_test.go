@@ -0,0 +1,45 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type testLimiter struct {
+	Limiter
+	clock  *testClock
+	server *miniredis.Miniredis
+}
+
+func (l *testLimiter) Advance(d time.Duration) {
+	l.clock.Advance(d)
+	if l.server != nil {
+		l.server.SetTime(l.clock.Now())
+		l.server.FastForward(d)
+	}
+}
+
+func testLimiters() map[string]func(t *testing.T) *testLimiter {
+	return map[string]func(t *testing.T) *testLimiter{
+		"in memory": func(t *testing.T) *testLimiter {
+			clock := newTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(clock.Now))
+			t.Cleanup(limiter.Stop)
+			return &testLimiter{Limiter: limiter, clock: clock}
+		},
+		"redis datasource": func(t *testing.T) *testLimiter {
+			server, client := newTestRedis(t)
+			clock := newTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			limiter := NewRateLimiter(NewRedisDatasource(client), NewTimeSleeper(), WithClock(clock.Now))
+			t.Cleanup(limiter.Stop)
+			return &testLimiter{Limiter: limiter, clock: clock, server: server}
+		},
+		"redis": func(t *testing.T) *testLimiter {
+			server, client := newTestRedis(t)
+			clock := newTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			return &testLimiter{Limiter: NewRedisLimiter(client, WithClock(clock.Now)), clock: clock, server: server}
+		},
+	}
+}
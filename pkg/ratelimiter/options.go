@@ -0,0 +1,37 @@
+package ratelimiter
+
+import "time"
+
+type options struct {
+	keyspace Keyspace
+	timeout  time.Duration
+	now      func() time.Time
+}
+
+type Option func(*options)
+
+func newOptions(opts []Option) *options {
+	o := &options{keyspace: NewKeyspace(DefaultKeyPrefix), now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.keyspace = NewKeyspace(prefix)
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
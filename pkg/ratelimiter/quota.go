@@ -0,0 +1,92 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrQuotaExceeded      = fmt.Errorf("%w: the quota for the current period is exhausted", ErrMaxRequests)
+	ErrUnknownQuotaPeriod = errors.New("unknown quota period")
+)
+
+type QuotaPeriod string
+
+const (
+	QuotaDaily   QuotaPeriod = "day"
+	QuotaMonthly QuotaPeriod = "month"
+)
+
+type QuotaConfig struct {
+	Limit    int
+	Period   QuotaPeriod
+	Location *time.Location
+}
+
+func NewQuotaConfig(limit int, period QuotaPeriod, location *time.Location) (*QuotaConfig, error) {
+	if period != QuotaDaily && period != QuotaMonthly {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuotaPeriod, period)
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return &QuotaConfig{Limit: limit, Period: period, Location: location}, nil
+}
+
+func (q *QuotaConfig) location() *time.Location {
+	if q.Location == nil {
+		return time.UTC
+	}
+	return q.Location
+}
+
+func (q *QuotaConfig) start(now time.Time) time.Time {
+	year, month, day := now.In(q.location()).Date()
+	if q.Period == QuotaMonthly {
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, q.location())
+}
+
+func (q *QuotaConfig) end(now time.Time) time.Time {
+	if q.Period == QuotaMonthly {
+		return q.start(now).AddDate(0, 1, 0)
+	}
+	return q.start(now).AddDate(0, 0, 1)
+}
+
+func (q *QuotaConfig) maxPeriod() time.Duration {
+	if q.Period == QuotaMonthly {
+		return 32 * 24 * time.Hour
+	}
+	return 25 * time.Hour
+}
+
+func (c *ClientRateLimiter) quotaUsed(now time.Time) int {
+	if !c.QuotaStart.Equal(c.Quota.start(now)) {
+		return 0
+	}
+	return c.QuotaUsed
+}
+
+func (c *ClientRateLimiter) hasQuotaFor(now time.Time, cost int) bool {
+	return c.Quota == nil || c.quotaUsed(now)+cost <= c.Quota.Limit
+}
+
+func (c *ClientRateLimiter) consumeQuota(now time.Time, cost int) {
+	if c.Quota == nil {
+		return
+	}
+	c.QuotaUsed = c.quotaUsed(now) + cost
+	c.QuotaStart = c.Quota.start(now)
+}
+
+func (c *ClientRateLimiter) quotaStatus(decision *Decision, now time.Time) {
+	if c.Quota == nil {
+		return
+	}
+	decision.QuotaLimit = c.Quota.Limit
+	decision.QuotaRemaining = remaining(c.Quota.Limit, float64(c.quotaUsed(now)))
+	decision.QuotaReset = c.Quota.end(now)
+}
@@ -0,0 +1,148 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingDatasource struct {
+	*jsonDatasource
+	sets int
+}
+
+func (d *countingDatasource) Set(key string, data *ClientRateLimiter) error {
+	d.sets++
+	return d.jsonDatasource.Set(key, data)
+}
+
+func TestQuota(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	assert.NoError(t, err)
+
+	t.Run("should reject unknown periods", func(t *testing.T) {
+		_, err := NewQuotaConfig(10, "year", nil)
+		assert.ErrorIs(t, err, ErrUnknownQuotaPeriod)
+	})
+
+	t.Run("should align the periods to the calendar of the timezone", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+
+		daily, err := NewQuotaConfig(10, QuotaDaily, saoPaulo)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, saoPaulo), daily.start(now))
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, saoPaulo), daily.end(now))
+
+		monthly, err := NewQuotaConfig(10, QuotaMonthly, saoPaulo)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, saoPaulo), monthly.start(now))
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, saoPaulo), monthly.end(now))
+	})
+
+	t.Run("should enforce the quota and reset it on the next period", func(t *testing.T) {
+		clock := newTestClock(time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC))
+		limiter := NewRateLimiter(newJSONDatasource(), NewTimeSleeper(), WithClock(clock.Now))
+		defer limiter.Stop()
+
+		tokenConfig := NewRateLimiterConfigByToken(100, 0, "API_KEY")
+		tokenConfig.Quota, err = NewQuotaConfig(3, QuotaMonthly, time.UTC)
+		assert.NoError(t, err)
+		config := NewRateLimiterConfig(nil, tokenConfig)
+
+		for i := 0; i < 3; i++ {
+			decision, err := limiter.HandleRequest("127.0.0.1", "abc1234", config)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, decision.QuotaLimit)
+			assert.Equal(t, 2-i, decision.QuotaRemaining)
+			assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), decision.QuotaReset)
+		}
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "abc1234", config)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.Equal(t, time.Hour, decision.RetryAfter)
+
+		clock.Set(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+		decision, err = limiter.HandleRequest("127.0.0.1", "abc1234", config)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, decision.QuotaRemaining)
+	})
+
+	t.Run("should keep the quota across limiter restarts", func(t *testing.T) {
+		datasource := newJSONDatasource()
+
+		ipConfig := NewRateLimiterConfigByIP(100, 0)
+		ipConfig.Quota, err = NewQuotaConfig(2, QuotaDaily, saoPaulo)
+		assert.NoError(t, err)
+		config := NewRateLimiterConfig(ipConfig, nil)
+
+		first := NewRateLimiter(datasource, NewTimeSleeper())
+		assert.NoError(t, requestErr(first.HandleRequest("127.0.0.1", "", config)))
+		assert.NoError(t, requestErr(first.HandleRequest("127.0.0.1", "", config)))
+
+		second := NewRateLimiter(datasource, NewTimeSleeper())
+		assert.ErrorIs(t, requestErr(second.HandleRequest("127.0.0.1", "", config)), ErrQuotaExceeded)
+	})
+
+	t.Run("should not rewrite the client config of a serialized quota", func(t *testing.T) {
+		datasource := &countingDatasource{jsonDatasource: newJSONDatasource()}
+		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
+
+		ipConfig := NewRateLimiterConfigByIP(100, 0)
+		ipConfig.Quota, err = NewQuotaConfig(10, QuotaDaily, saoPaulo)
+		assert.NoError(t, err)
+		config := NewRateLimiterConfig(ipConfig, nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		sets := datasource.sets
+
+		for i := 0; i < 3; i++ {
+			decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+			assert.NoError(t, err)
+			assert.Equal(t, 8-i, decision.QuotaRemaining)
+		}
+		assert.Equal(t, sets+3, datasource.sets)
+	})
+
+	t.Run("should not consume the quota of rejected requests", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		ipConfig := NewRateLimiterConfigByIP(1, 0)
+		ipConfig.Window = time.Hour
+		ipConfig.Quota, err = NewQuotaConfig(10, QuotaDaily, time.UTC)
+		assert.NoError(t, err)
+		config := NewRateLimiterConfig(ipConfig, nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.NotErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, 9, decision.QuotaRemaining)
+	})
+
+	t.Run("should enforce the quota atomically in redis", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		tokenConfig := NewRateLimiterConfigByToken(100, 0, "API_KEY")
+		tokenConfig.Quota, err = NewQuotaConfig(2, QuotaMonthly, saoPaulo)
+		assert.NoError(t, err)
+		config := NewRateLimiterConfig(nil, tokenConfig)
+
+		for i := 0; i < 2; i++ {
+			decision, err := limiter.HandleRequest("127.0.0.1", "abc1234", config)
+			assert.NoError(t, err)
+			assert.Equal(t, 1-i, decision.QuotaRemaining)
+		}
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "abc1234", config)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, 0, decision.QuotaRemaining)
+		assert.True(t, decision.RetryAfter > 0)
+		assert.True(t, server.TTL("ratelimiter:token:abc1234") >= 24*time.Hour)
+	})
+}
@@ -1,6 +1,7 @@
 package ratelimiter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -13,6 +14,19 @@ var (
 	ErrNilConfig              = errors.New("config cannot be nil")
 )
 
+const defaultWindow = 1 * time.Second
+
+func isConfigError(err error) bool {
+	return errors.Is(err, ErrNilConfig) || errors.Is(err, ErrTokenRegistry)
+}
+
+func normalizeCost(cost int) int {
+	if cost < 1 {
+		return 1
+	}
+	return cost
+}
+
 type Sleeper interface {
 	Sleep(d time.Duration)
 }
@@ -21,31 +35,59 @@ type Datasource interface {
 	Set(key string, data *ClientRateLimiter) error
 	Get(key string) (*ClientRateLimiter, error)
 	Has(key string) bool
+}
+
+type ListableDatasource interface {
+	Datasource
 	All() (map[string]*ClientRateLimiter, error)
+	Delete(key string) error
+}
+
+type Limiter interface {
+	HandleRequest(ip, token string, config *RateLimiterConfig) (*Decision, error)
+	HandleRequestContext(ctx context.Context, ip, token string, config *RateLimiterConfig) (*Decision, error)
+	HandleRequestN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error)
 }
 
 type RateLimiter struct {
+	keyspace   Keyspace
 	datasource Datasource
+	store      ContextDatasource
 	sleeper    Sleeper
+	now        func() time.Time
+	done       chan struct{}
+	stopOnce   sync.Once
 }
 
 type BaseLimiterConfig struct {
 	RequestesPerSecond int
 	BlockUserFor       time.Duration
+	Window             time.Duration
+	Burst              int
+	RefillRate         float64
+	Quota              *QuotaConfig
 }
 
 type RateLimiterConfigByIP struct {
 	BaseLimiterConfig
+	IPv4Prefix int
+	IPv6Prefix int
 }
 
 type RateLimiterConfigByToken struct {
 	BaseLimiterConfig
-	Key string
+	Key      string
+	Registry TokenRegistry
 }
 
 type RateLimiterConfig struct {
 	ConfigByIP    *RateLimiterConfigByIP
 	ConfigByToken *RateLimiterConfigByToken
+	Strategy      Strategy
+	ConfigGlobal  *RateLimiterConfigGlobal
+	Buckets       []*RateLimiterConfigByBucket
+	Scope         string
+	Mode          CombinationMode
 }
 
 func NewRateLimiterConfigByIP(requestesPerSecond int, blockUserFor time.Duration) *RateLimiterConfigByIP {
@@ -74,61 +116,118 @@ func NewRateLimiterConfig(configByIP *RateLimiterConfigByIP, configByToken *Rate
 	}
 }
 
-func NewRateLimiter(datasource Datasource, sleeper Sleeper) *RateLimiter {
-	limiter := &RateLimiter{datasource: datasource, sleeper: sleeper}
+func (c *RateLimiterConfig) limitFor(ctx context.Context, ip, token string) (Dimension, string, *BaseLimiterConfig, error) {
+	if token != "" && c.ConfigByToken != nil {
+		limits, err := c.ConfigByToken.limitsFor(ctx, token)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return DimensionToken, token, limits, nil
+	}
+	if c.ConfigByIP != nil {
+		return DimensionIP, c.ConfigByIP.aggregate(ip), &c.ConfigByIP.BaseLimiterConfig, nil
+	}
+	return "", "", nil, ErrNilConfig
+}
 
-	go limiter.clearRequests()
+func (c *RateLimiterConfig) strategy() Strategy {
+	if c.Strategy == nil {
+		return NewFixedWindow()
+	}
+	return c.Strategy
+}
+
+func NewRateLimiter(datasource Datasource, sleeper Sleeper, opts ...Option) *RateLimiter {
+	o := newOptions(opts)
+	limiter := &RateLimiter{
+		keyspace:   o.keyspace,
+		datasource: datasource,
+		store:      withContext(datasource, o.timeout),
+		sleeper:    sleeper,
+		now:        o.now,
+		done:       make(chan struct{}),
+	}
+
+	if _, ok := datasource.(ListableDatasource); ok {
+		go limiter.clearRequests()
+	}
 
 	return limiter
 }
 
+func (r *RateLimiter) Stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
+
+func (r *RateLimiter) stopped() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (r *RateLimiter) clear() {
 	r.sleeper.Sleep(1 * time.Second)
-	clients, err := r.datasource.All()
+	datasource, ok := r.datasource.(ListableDatasource)
+	if !ok || r.stopped() {
+		return
+	}
+	clients, err := datasource.All()
 	if err != nil {
 		panic(err)
 	}
+	now := r.now()
 	for key, client := range clients {
-		client.Mux.Lock()
-		defer client.Mux.Unlock()
-		client.clearRequests()
-		if client.hasBlockingExpired() {
-			client.resetBlock()
-		}
-		r.datasource.Set(key, client)
+		r.clearClient(datasource, key, client, now)
 	}
 }
 
+func (r *RateLimiter) clearClient(datasource ListableDatasource, key string, client *ClientRateLimiter, now time.Time) {
+	client.Mux.Lock()
+	defer client.Mux.Unlock()
+	if client.hasExpired(now) {
+		datasource.Delete(key)
+		return
+	}
+	client.clearRequests(now)
+	if client.hasBlockingExpired(now) {
+		client.resetBlock()
+	}
+	r.datasource.Set(key, client)
+}
+
 func (r *RateLimiter) clearRequests() {
-	for {
+	for !r.stopped() {
 		r.clear()
 	}
 }
 
-func (r *RateLimiter) setConfigBy(key string, config *BaseLimiterConfig) (*ClientRateLimiter, error) {
+func (r *RateLimiter) setConfigBy(ctx context.Context, key string, config *BaseLimiterConfig) (*ClientRateLimiter, error) {
 	if config == nil {
 		return nil, ErrNilConfig
 	}
 
-	var client *ClientRateLimiter
+	client, err := r.store.GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
 
-	if found := r.datasource.Has(key); !found {
+	if client == nil {
 		client = newClientLimiter(config.RequestesPerSecond, config.BlockUserFor)
-		if err := r.datasource.Set(key, client); err != nil {
+		client.configure(config)
+		if err := r.store.SetContext(ctx, key, client); err != nil {
 			return nil, err
 		}
 		return client, nil
 	}
 
-	client, err := r.datasource.Get(key)
-	if err != nil {
-		return nil, err
-	}
+	client.Quota = config.Quota
 
-	if client.RequestsPerSecond != config.RequestesPerSecond {
-		client.RequestsPerSecond = config.RequestesPerSecond
-		client.BlockUserFor = config.BlockUserFor
-		if err := r.datasource.Set(key, client); err != nil {
+	if !client.isConfiguredWith(config) {
+		client.configure(config)
+		if err := r.store.SetContext(ctx, key, client); err != nil {
 			return nil, err
 		}
 	}
@@ -136,7 +235,7 @@ func (r *RateLimiter) setConfigBy(key string, config *BaseLimiterConfig) (*Clien
 	return client, nil
 }
 
-func (r *RateLimiter) getClient(ip, token string, config *RateLimiterConfig) (*ClientRateLimiter, string, error) {
+func (r *RateLimiter) getClient(ctx context.Context, ip, token string, config *RateLimiterConfig) (*ClientRateLimiter, string, error) {
 	if config == nil {
 		return nil, "", ErrNilConfig
 	}
@@ -151,51 +250,137 @@ func (r *RateLimiter) getClient(ip, token string, config *RateLimiterConfig) (*C
 	var key string
 
 	if ipConfig != nil {
-		ipClient, err := r.setConfigBy(ip, &ipConfig.BaseLimiterConfig)
+		ipKey := r.keyspace.Scoped(config.Scope).Key(DimensionIP, ipConfig.aggregate(ip))
+		ipClient, err := r.setConfigBy(ctx, ipKey, &ipConfig.BaseLimiterConfig)
 		if err != nil {
 			return nil, "", fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
 		}
 		client = ipClient
-		key = ip
+		key = ipKey
 	}
 
 	if token != "" && tokenConfig != nil {
-		tokenClient, err := r.setConfigBy(token, &tokenConfig.BaseLimiterConfig)
+		tokenLimits, err := tokenConfig.limitsFor(ctx, token)
+		if err != nil {
+			return nil, "", err
+		}
+
+		tokenKey := r.keyspace.Scoped(config.Scope).Key(DimensionToken, token)
+		tokenClient, err := r.setConfigBy(ctx, tokenKey, tokenLimits)
 
 		if err != nil {
 			return nil, "", fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
 		}
 
 		client = tokenClient
-		key = token
+		key = tokenKey
+	}
+
+	if client == nil {
+		return nil, "", ErrNilConfig
 	}
 
 	return client, key, nil
 }
 
-func (r *RateLimiter) HandleRequest(ip, token string, config *RateLimiterConfig) error {
-	client, key, err := r.getClient(ip, token, config)
+func (r *RateLimiter) HandleRequest(ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return r.HandleRequestContext(context.Background(), ip, token, config)
+}
+
+func (r *RateLimiter) HandleRequestContext(ctx context.Context, ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return r.HandleRequestN(ctx, ip, token, config, 1)
+}
+
+func (r *RateLimiter) HandleRequestN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	return r.handle(ctx, ip, token, config, cost, true)
+}
+
+func (r *RateLimiter) peekN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	return r.handle(ctx, ip, token, config, cost, false)
+}
+
+func (r *RateLimiter) handle(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int, charge bool) (*Decision, error) {
+	if config != nil && (config.mode() != CombineTokenOverridesIP || config.hasSharedLimits()) {
+		return r.handleComposite(ctx, ip, token, config, cost, charge)
+	}
+
+	client, key, err := r.getClient(ctx, ip, token, config)
 
 	if err != nil {
-		return ErrGettingRateLimiterData
+		if errors.Is(err, ErrGettingRateLimiterData) || isConfigError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
 	}
 
-	err = client.verifyAndBlockUser(r.datasource, key)
+	var decision *Decision
+	if charge {
+		decision, err = client.verifyAndBlockUser(ctx, r.store, key, config.strategy(), r.now(), cost)
+	} else {
+		decision, err = client.peek(config.strategy(), r.now(), cost)
+	}
+
+	if err != nil && !errors.Is(err, ErrMaxRequests) {
+		return nil, fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
+	}
+
+	return decision, err
+}
 
+func (r *RateLimiter) handleComposite(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int, charge bool) (*Decision, error) {
+	limits, err := config.limits(ctx, ip, token)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	strategy := config.strategy()
+
+	evaluate := evaluation(func(key string, limit dimensionLimit, charge bool) (*Decision, error) {
+		client, err := r.setConfigBy(ctx, key, limit.config)
+		if err != nil {
+			return nil, err
+		}
+		if !charge {
+			return client.peek(strategy, r.now(), cost)
+		}
+		return client.verifyAndBlockUser(ctx, r.store, key, strategy, r.now(), cost)
+	})
+
+	if !charge {
+		evaluate = evaluate.peeking()
+	}
+
+	decision, err := enforce(r.keyspace, config, limits, evaluate)
+
+	if err != nil && !errors.Is(err, ErrMaxRequests) {
+		return nil, fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
+	}
+
+	return decision, err
 }
 
 type ClientRateLimiter struct {
-	RequestsPerSecond int           `json:"requestsPerSecond"`
-	BlockUserFor      time.Duration `json:"blockUserFor"`
-	Blocked           bool          `json:"blocked"`
-	BlockedAt         time.Time     `json:"blockedAt"`
-	TotalRequests     int           `json:"totalRequests"`
-	Mux               sync.Mutex    `json:"-"`
+	RequestsPerSecond      int           `json:"requestsPerSecond"`
+	BlockUserFor           time.Duration `json:"blockUserFor"`
+	Blocked                bool          `json:"blocked"`
+	BlockedAt              time.Time     `json:"blockedAt"`
+	TotalRequests          int           `json:"totalRequests"`
+	Window                 time.Duration `json:"window,omitempty"`
+	Burst                  int           `json:"burst,omitempty"`
+	RefillRate             float64       `json:"refillRate,omitempty"`
+	Log                    []time.Time   `json:"log,omitempty"`
+	WindowStart            time.Time     `json:"windowStart"`
+	WindowRequests         int           `json:"windowRequests"`
+	PreviousWindowRequests int           `json:"previousWindowRequests"`
+	Tokens                 float64       `json:"tokens"`
+	LastRefill             time.Time     `json:"lastRefill"`
+	Level                  float64       `json:"level"`
+	LastLeak               time.Time     `json:"lastLeak"`
+	Quota                  *QuotaConfig  `json:"-"`
+	QuotaStart             time.Time     `json:"quotaStart"`
+	QuotaUsed              int           `json:"quotaUsed"`
+	LastSeen               time.Time     `json:"lastSeen"`
+	Mux                    sync.Mutex    `json:"-"`
 }
 
 func newClientLimiter(rps int, blockDuration time.Duration) *ClientRateLimiter {
@@ -206,40 +391,137 @@ func newClientLimiter(rps int, blockDuration time.Duration) *ClientRateLimiter {
 	}
 }
 
-func (c *ClientRateLimiter) verifyAndBlockUser(datasource Datasource, key string) error {
+func (c *ClientRateLimiter) configure(config *BaseLimiterConfig) {
+	c.RequestsPerSecond = config.RequestesPerSecond
+	c.BlockUserFor = config.BlockUserFor
+	c.Window = config.Window
+	c.Burst = config.Burst
+	c.RefillRate = config.RefillRate
+	c.Quota = config.Quota
+}
+
+func (c *ClientRateLimiter) isConfiguredWith(config *BaseLimiterConfig) bool {
+	return c.RequestsPerSecond == config.RequestesPerSecond &&
+		c.BlockUserFor == config.BlockUserFor &&
+		c.Window == config.Window &&
+		c.Burst == config.Burst &&
+		c.RefillRate == config.RefillRate
+}
+
+func (c *ClientRateLimiter) verifyAndBlockUser(ctx context.Context, datasource ContextDatasource, key string, strategy Strategy, now time.Time, cost int) (*Decision, error) {
 	c.Mux.Lock()
 	defer c.Mux.Unlock()
 
+	c.LastSeen = now
+
+	if c.isBlocked() && !c.hasBlockingExpired(now) {
+		return newDecision(c, strategy, now, false), ErrMaxRequests
+	}
+
+	decision, err := c.evaluate(strategy, now, cost, true)
+
+	if setErr := datasource.SetContext(ctx, key, c); setErr != nil {
+		return nil, setErr
+	}
+
+	return decision, err
+}
+
+func (c *ClientRateLimiter) peek(strategy Strategy, now time.Time, cost int) (*Decision, error) {
+	probe := c.snapshot()
+
+	if probe.isBlocked() && !probe.hasBlockingExpired(now) {
+		return newDecision(probe, strategy, now, false), ErrMaxRequests
+	}
+
+	return probe.evaluate(strategy, now, cost, false)
+}
+
+func (c *ClientRateLimiter) evaluate(strategy Strategy, now time.Time, cost int, penalize bool) (*Decision, error) {
 	if c.isBlocked() {
-		if c.hasBlockingExpired() {
-			c.resetBlock()
-			if err := datasource.Set(key, c); err != nil {
-				return err
-			}
-		} else {
-			return ErrMaxRequests
-		}
+		c.resetBlock()
 	}
 
-	c.TotalRequests += 1
+	cost = normalizeCost(cost)
+
+	if !c.hasQuotaFor(now, cost) {
+		decision := newDecision(c, strategy, now, false)
+		decision.Reset = c.Quota.end(now)
+		decision.RetryAfter = decision.Reset.Sub(now)
+		return decision, ErrQuotaExceeded
+	}
 
-	if c.shouldBlock() {
-		c.block()
-		if err := datasource.Set(key, c); err != nil {
-			return err
+	if !strategy.AllowN(c, now, cost) {
+		if penalize && c.BlockUserFor > 0 {
+			c.block(now)
 		}
-		return ErrMaxRequests
+		return newDecision(c, strategy, now, false), ErrMaxRequests
+	}
+
+	c.consumeQuota(now, cost)
+
+	return newDecision(c, strategy, now, true), nil
+}
+
+func (c *ClientRateLimiter) snapshot() *ClientRateLimiter {
+	c.Mux.Lock()
+	defer c.Mux.Unlock()
+
+	return &ClientRateLimiter{
+		RequestsPerSecond:      c.RequestsPerSecond,
+		BlockUserFor:           c.BlockUserFor,
+		Blocked:                c.Blocked,
+		BlockedAt:              c.BlockedAt,
+		TotalRequests:          c.TotalRequests,
+		Window:                 c.Window,
+		Burst:                  c.Burst,
+		RefillRate:             c.RefillRate,
+		Log:                    append([]time.Time(nil), c.Log...),
+		WindowStart:            c.WindowStart,
+		WindowRequests:         c.WindowRequests,
+		PreviousWindowRequests: c.PreviousWindowRequests,
+		Tokens:                 c.Tokens,
+		LastRefill:             c.LastRefill,
+		Level:                  c.Level,
+		LastLeak:               c.LastLeak,
+		Quota:                  c.Quota,
+		QuotaStart:             c.QuotaStart,
+		QuotaUsed:              c.QuotaUsed,
+		LastSeen:               c.LastSeen,
 	}
+}
+
+func (c *ClientRateLimiter) window() time.Duration {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return defaultWindow
+}
 
-	if err := datasource.Set(key, c); err != nil {
-		return err
+func (c *ClientRateLimiter) ttl() time.Duration {
+	ttl := 2 * c.window()
+	if c.BlockUserFor > ttl {
+		ttl = c.BlockUserFor
 	}
+	if refill := time.Duration(float64(c.bucketCapacity()) / c.bucketRefillRate() * float64(time.Second)); refill > ttl {
+		ttl = refill
+	}
+	if c.Quota != nil && c.Quota.maxPeriod() > ttl {
+		ttl = c.Quota.maxPeriod()
+	}
+	return ttl
+}
 
-	return nil
+func (c *ClientRateLimiter) hasExpired(now time.Time) bool {
+	return now.Sub(c.LastSeen) > c.ttl()
 }
 
-func (c *ClientRateLimiter) clearRequests() {
-	if c.TotalRequests <= c.RequestsPerSecond {
+func (c *ClientRateLimiter) hasWindowExpired(now time.Time) bool {
+	return now.Sub(c.WindowStart) >= c.window()
+}
+
+func (c *ClientRateLimiter) clearRequests(now time.Time) {
+	if c.hasWindowExpired(now) {
 		c.TotalRequests = 0
 	}
 }
@@ -248,8 +530,8 @@ func (c *ClientRateLimiter) isBlocked() bool {
 	return c.Blocked
 }
 
-func (c *ClientRateLimiter) hasBlockingExpired() bool {
-	return time.Since(c.BlockedAt) > c.BlockUserFor
+func (c *ClientRateLimiter) hasBlockingExpired(now time.Time) bool {
+	return now.Sub(c.BlockedAt) > c.BlockUserFor
 }
 
 func (c *ClientRateLimiter) resetBlock() {
@@ -258,11 +540,7 @@ func (c *ClientRateLimiter) resetBlock() {
 	c.BlockedAt = time.Time{}
 }
 
-func (c *ClientRateLimiter) shouldBlock() bool {
-	return c.TotalRequests > c.RequestsPerSecond
-}
-
-func (c *ClientRateLimiter) block() {
+func (c *ClientRateLimiter) block(now time.Time) {
 	c.Blocked = true
-	c.BlockedAt = time.Now()
+	c.BlockedAt = now
 }
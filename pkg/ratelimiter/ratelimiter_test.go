@@ -1,6 +1,7 @@
 package ratelimiter
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -33,6 +34,11 @@ func (m *DatasourceMock) All() (map[string]*ClientRateLimiter, error) {
 	return args.Get(0).(map[string]*ClientRateLimiter), args.Error(1)
 }
 
+func (m *DatasourceMock) Delete(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
 type TimeSleeperMock struct {
 	mock.Mock
 }
@@ -45,7 +51,8 @@ func TestHandleRequest(t *testing.T) {
 	t.Run("setConfigBy", func(t *testing.T) {
 		t.Run("should return an error when the config is nil", func(t *testing.T) {
 			limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
-			_, err := limiter.setConfigBy("key", nil)
+			defer limiter.Stop()
+			_, err := limiter.setConfigBy(context.Background(), "key", nil)
 			assert.NotNil(t, err)
 			assert.ErrorIs(t, err, ErrNilConfig)
 		})
@@ -57,8 +64,9 @@ func TestHandleRequest(t *testing.T) {
 			assert.Nil(t, client)
 
 			limiter := NewRateLimiter(datasource, NewTimeSleeper())
+			defer limiter.Stop()
 
-			client, err := limiter.setConfigBy(key, &BaseLimiterConfig{
+			client, err := limiter.setConfigBy(context.Background(), key, &BaseLimiterConfig{
 				RequestesPerSecond: 10,
 				BlockUserFor:       10 * time.Second,
 			})
@@ -81,8 +89,9 @@ func TestHandleRequest(t *testing.T) {
 			assert.NoError(t, err)
 
 			limiter := NewRateLimiter(datasource, NewTimeSleeper())
+			defer limiter.Stop()
 
-			client, err := limiter.setConfigBy(key, &BaseLimiterConfig{
+			client, err := limiter.setConfigBy(context.Background(), key, &BaseLimiterConfig{
 				RequestesPerSecond: 10,
 				BlockUserFor:       10 * time.Second,
 			})
@@ -102,12 +111,12 @@ func TestHandleRequest(t *testing.T) {
 			errNotFound := errors.New("not found")
 
 			datasource := &DatasourceMock{}
-			datasource.On("Has", key).Return(true)
 			datasource.On("Get", key).Return(&ClientRateLimiter{}, errNotFound)
 
 			limiter := NewRateLimiter(datasource, NewTimeSleeper())
+			defer limiter.Stop()
 
-			client, err := limiter.setConfigBy(key, &BaseLimiterConfig{
+			client, err := limiter.setConfigBy(context.Background(), key, &BaseLimiterConfig{
 				RequestesPerSecond: 10,
 				BlockUserFor:       10 * time.Second,
 			})
@@ -124,31 +133,46 @@ func TestHandleRequest(t *testing.T) {
 
 			datasource := &DatasourceMock{}
 			limiter := NewRateLimiter(datasource, NewTimeSleeper())
+			defer limiter.Stop()
+
+			client, key, err := limiter.getClient(context.Background(), ip, "", nil)
+
+			assert.Nil(t, client)
+			assert.Empty(t, key)
+			assert.ErrorIs(t, err, ErrNilConfig)
+		})
+		t.Run("should return an error when no dimension applies to the request", func(t *testing.T) {
+			limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+			defer limiter.Stop()
+
+			config := NewRateLimiterConfig(nil, NewRateLimiterConfigByToken(10, 0, "API_KEY"))
 
-			client, key, err := limiter.getClient(ip, "", nil)
+			client, key, err := limiter.getClient(context.Background(), "127.0.0.1", "", config)
 
 			assert.Nil(t, client)
 			assert.Empty(t, key)
 			assert.ErrorIs(t, err, ErrNilConfig)
+			assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrNilConfig)
 		})
 		t.Run("should set the config by ip when the token config is not set", func(t *testing.T) {
 			ip := "127.0.0.1"
 			token := ""
+			ipKey := "ratelimiter:ip:" + ip
 
 			clientLimiter := newClientLimiter(10, 10*time.Second)
 
 			datasource := &DatasourceMock{}
-			datasource.On("Get", ip).Return(clientLimiter, nil)
-			datasource.On("Has", ip).Return(true)
+			datasource.On("Get", ipKey).Return(clientLimiter, nil)
 
 			limiter := NewRateLimiter(datasource, NewTimeSleeper())
+			defer limiter.Stop()
 
-			client, key, err := limiter.getClient(ip, token, NewRateLimiterConfig(
+			client, key, err := limiter.getClient(context.Background(), ip, token, NewRateLimiterConfig(
 				NewRateLimiterConfigByIP(10, 10*time.Second), nil,
 			))
 
 			assert.NoError(t, err)
-			assert.Equal(t, ip, key)
+			assert.Equal(t, ipKey, key)
 			assert.NotNil(t, client)
 			assert.Equal(t, clientLimiter, client)
 		})
@@ -156,24 +180,27 @@ func TestHandleRequest(t *testing.T) {
 		t.Run("should set the config by token when both configs are provided", func(t *testing.T) {
 			ip := "127.0.0.1"
 			token := "abc1234"
+			ipKey := "ratelimiter:ip:" + ip
+			tokenKey := "ratelimiter:token:" + token
 
 			clientIpLimiter := newClientLimiter(10, 10*time.Second)
 			clientTokenLimiter := newClientLimiter(5, 30*time.Second)
 
 			datasourceMock := &DatasourceMock{}
 
-			datasourceMock.On("Get", ip).Return(clientIpLimiter, nil).Once().On("Has", ip).Return(true).Once()
-			datasourceMock.On("Get", token).Return(clientTokenLimiter, nil).Once().On("Has", token).Return(true).Once()
+			datasourceMock.On("Get", ipKey).Return(clientIpLimiter, nil).Once()
+			datasourceMock.On("Get", tokenKey).Return(clientTokenLimiter, nil).Once()
 
 			limiter := NewRateLimiter(datasourceMock, NewTimeSleeper())
+			defer limiter.Stop()
 
-			client, key, err := limiter.getClient(ip, token, NewRateLimiterConfig(
+			client, key, err := limiter.getClient(context.Background(), ip, token, NewRateLimiterConfig(
 				NewRateLimiterConfigByIP(10, 10*time.Second),
 				NewRateLimiterConfigByToken(5, 30*time.Second, "API_KEY"),
 			))
 
 			assert.NoError(t, err)
-			assert.Equal(t, token, key)
+			assert.Equal(t, tokenKey, key)
 			assert.NotNil(t, client)
 			assert.Equal(t, clientTokenLimiter, client)
 		})
@@ -181,24 +208,27 @@ func TestHandleRequest(t *testing.T) {
 		t.Run("should set the config by token when it's the only config provided", func(t *testing.T) {
 			ip := "127.0.0.1"
 			token := "abc1234"
+			ipKey := "ratelimiter:ip:" + ip
+			tokenKey := "ratelimiter:token:" + token
 
 			clientIpLimiter := newClientLimiter(10, 10*time.Second)
 			clientTokenLimiter := newClientLimiter(5, 30*time.Second)
 
 			datasourceMock := &DatasourceMock{}
 
-			datasourceMock.On("Get", ip).Return(clientIpLimiter, nil).Once().On("Has", ip).Return(true).Once()
-			datasourceMock.On("Get", token).Return(clientTokenLimiter, nil).Once().On("Has", token).Return(true).Once()
+			datasourceMock.On("Get", ipKey).Return(clientIpLimiter, nil).Once()
+			datasourceMock.On("Get", tokenKey).Return(clientTokenLimiter, nil).Once()
 
 			limiter := NewRateLimiter(datasourceMock, NewTimeSleeper())
+			defer limiter.Stop()
 
-			client, key, err := limiter.getClient(ip, token, NewRateLimiterConfig(
+			client, key, err := limiter.getClient(context.Background(), ip, token, NewRateLimiterConfig(
 				nil,
 				NewRateLimiterConfigByToken(5, 30*time.Second, "API_KEY"),
 			))
 
 			assert.NoError(t, err)
-			assert.Equal(t, token, key)
+			assert.Equal(t, tokenKey, key)
 			assert.NotNil(t, client)
 			assert.Equal(t, clientTokenLimiter, client)
 		})
@@ -206,6 +236,7 @@ func TestHandleRequest(t *testing.T) {
 
 	t.Run("should return nil when the request is allowed", func(t *testing.T) {
 		ip := "127.0.0.1"
+		ipKey := "ratelimiter:ip:" + ip
 
 		config := NewRateLimiterConfig(
 			NewRateLimiterConfigByIP(10, 10*time.Second), nil,
@@ -213,16 +244,15 @@ func TestHandleRequest(t *testing.T) {
 
 		datasource := &DatasourceMock{}
 		datasource.
-			On("Get", ip).
-			Return(nil, errors.New("not found")).
-			On("Has", ip).
-			Return(false).
-			On("Set", ip, mock.Anything).
+			On("Get", ipKey).
+			Return((*ClientRateLimiter)(nil), nil).
+			On("Set", ipKey, mock.Anything).
 			Return(nil)
 
 		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
 
-		err := limiter.HandleRequest(ip, "", config)
+		_, err := limiter.HandleRequest(ip, "", config)
 
 		assert.NoError(t, err)
 	})
@@ -232,18 +262,19 @@ func TestHandleRequest(t *testing.T) {
 
 		datasource := NewInMemoryDatasource()
 		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
 		config := NewRateLimiterConfig(
 			NewRateLimiterConfigByIP(10, 10*time.Second), nil,
 		)
 
 		for i := 0; i < 10; i++ {
-			err := limiter.HandleRequest(ip, "", config)
+			_, err := limiter.HandleRequest(ip, "", config)
 			if err != nil {
 				t.Errorf("expected no error, got %v", err)
 			}
 		}
 
-		err := limiter.HandleRequest(ip, "", config)
+		_, err := limiter.HandleRequest(ip, "", config)
 
 		assert.ErrorIs(t, err, ErrMaxRequests)
 	})
@@ -254,19 +285,20 @@ func TestHandleRequest(t *testing.T) {
 
 		datasource := NewInMemoryDatasource()
 		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
 		config := NewRateLimiterConfig(
 			NewRateLimiterConfigByIP(10, 10*time.Second),
 			NewRateLimiterConfigByToken(5, 30*time.Second, "API_KEY"),
 		)
 
 		for i := 0; i < 5; i++ {
-			err := limiter.HandleRequest(ip, token, config)
+			_, err := limiter.HandleRequest(ip, token, config)
 			if err != nil {
 				t.Errorf("expected no error, got %v", err)
 			}
 		}
 
-		err := limiter.HandleRequest(ip, token, config)
+		_, err := limiter.HandleRequest(ip, token, config)
 
 		assert.ErrorIs(t, err, ErrMaxRequests)
 	})
@@ -281,6 +313,7 @@ func TestHandleRequest(t *testing.T) {
 		client.Blocked = true
 		client.BlockedAt = time.Now().Add(-31 * time.Second)
 		client.TotalRequests = 10
+		client.LastSeen = time.Now()
 
 		assert.True(t, client.isBlocked())
 
@@ -292,7 +325,8 @@ func TestHandleRequest(t *testing.T) {
 		}
 
 		limiter := NewRateLimiter(datasource, timeSleeper)
-		
+		defer limiter.Stop()
+
 		limiter.clear()
 
 		client, err = datasource.Get(ip)
@@ -304,4 +338,25 @@ func TestHandleRequest(t *testing.T) {
 		assert.False(t, client.isBlocked())
 		assert.Equal(t, 0, client.TotalRequests)
 	})
+
+	t.Run("should evict the clients that were not seen within their ttl", func(t *testing.T) {
+		timeSleeper := &TimeSleeperMock{}
+		timeSleeper.On("Sleep", 1*time.Second).Return()
+
+		datasource := NewInMemoryDatasource()
+		clock := newTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		limiter := NewRateLimiter(datasource, timeSleeper, WithClock(clock.Now))
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(5, 30*time.Second), nil)
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+
+		clock.Advance(30 * time.Second)
+		limiter.clear()
+		assert.True(t, datasource.Has("ratelimiter:ip:127.0.0.1"))
+
+		clock.Advance(time.Second)
+		limiter.clear()
+		assert.False(t, datasource.Has("ratelimiter:ip:127.0.0.1"))
+	})
 }
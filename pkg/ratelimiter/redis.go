@@ -3,8 +3,8 @@ package ratelimiter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"sync"
-	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -19,20 +19,31 @@ func NewRedisDatasource(client *redis.Client) *RedisDatasource {
 }
 
 func (d *RedisDatasource) Set(key string, data *ClientRateLimiter) error {
+	return d.SetContext(context.Background(), key, data)
+}
+
+func (d *RedisDatasource) SetContext(ctx context.Context, key string, data *ClientRateLimiter) error {
 	jsonData, err := json.Marshal(data)
 
 	if err != nil {
 		return err
 	}
 
-	if err := d.client.Set(context.Background(), key, string(jsonData), 60*time.Minute).Err(); err != nil {
+	if err := d.client.Set(ctx, key, string(jsonData), data.ttl()).Err(); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (d *RedisDatasource) Get(key string) (*ClientRateLimiter, error) {
-	data, err := d.client.Get(context.Background(), key).Result()
+	return d.GetContext(context.Background(), key)
+}
+
+func (d *RedisDatasource) GetContext(ctx context.Context, key string) (*ClientRateLimiter, error) {
+	data, err := d.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -48,25 +59,14 @@ func (d *RedisDatasource) Get(key string) (*ClientRateLimiter, error) {
 }
 
 func (d *RedisDatasource) Has(key string) bool {
-	client, _ := d.Get(key)
-	return client != nil
+	found, _ := d.HasContext(context.Background(), key)
+	return found
 }
 
-func (d *RedisDatasource) All() (map[string]*ClientRateLimiter, error) {
-	keys, err := d.client.Keys(context.Background(), "*").Result()
+func (d *RedisDatasource) HasContext(ctx context.Context, key string) (bool, error) {
+	found, err := d.client.Exists(ctx, key).Result()
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-
-	clients := make(map[string]*ClientRateLimiter)
-
-	for _, key := range keys {
-		client, err := d.Get(key)
-		if err != nil {
-			return nil, err
-		}
-		clients[key] = client
-	}
-
-	return clients, nil
+	return found > 0, nil
 }
@@ -0,0 +1,95 @@
+package ratelimiter
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+//go:embed redis_concurrency.lua
+var redisConcurrencySource string
+
+var redisConcurrencyScript = redis.NewScript(redisConcurrencySource)
+
+const defaultLeaseTTL = 30 * time.Second
+
+type RedisConcurrencyLimiter struct {
+	keyspace Keyspace
+	client   *redis.Client
+	timeout  time.Duration
+	leaseTTL time.Duration
+}
+
+func NewRedisConcurrencyLimiter(client *redis.Client, leaseTTL time.Duration, opts ...Option) *RedisConcurrencyLimiter {
+	o := newOptions(opts)
+	if leaseTTL <= 0 {
+		leaseTTL = defaultLeaseTTL
+	}
+	return &RedisConcurrencyLimiter{keyspace: o.keyspace, client: client, timeout: o.timeout, leaseTTL: leaseTTL}
+}
+
+func (l *RedisConcurrencyLimiter) Acquire(ctx context.Context, key string, limit int) (*Lease, error) {
+	key = l.keyspace.Key(DimensionConcurrency, key)
+
+	id, err := leaseID()
+	if err != nil {
+		return nil, err
+	}
+
+	acquired, err := l.renew(ctx, key, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrMaxConcurrency
+	}
+
+	done := make(chan struct{})
+	go l.keepAlive(key, id, limit, done)
+
+	return newLease(func(ctx context.Context) error {
+		close(done)
+
+		ctx, cancel := withTimeout(ctx, l.timeout)
+		defer cancel()
+
+		return l.client.ZRem(ctx, key, id).Err()
+	}), nil
+}
+
+func (l *RedisConcurrencyLimiter) renew(ctx context.Context, key, id string, limit int) (bool, error) {
+	ctx, cancel := withTimeout(ctx, l.timeout)
+	defer cancel()
+
+	result, err := redisConcurrencyScript.Run(ctx, l.client, []string{key}, limit, id, l.leaseTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+func (l *RedisConcurrencyLimiter) keepAlive(key, id string, limit int, done chan struct{}) {
+	ticker := time.NewTicker(l.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			l.renew(context.Background(), key, id, limit)
+		}
+	}
+}
+
+func leaseID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,18 @@
+local key = KEYS[1]
+
+local limit = tonumber(ARGV[1])
+local lease = ARGV[2]
+local ttl = tonumber(ARGV[3])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+
+if redis.call('ZSCORE', key, lease) == false and redis.call('ZCARD', key) >= limit then
+  return 0
+end
+
+redis.call('ZADD', key, now + ttl, lease)
+redis.call('PEXPIRE', key, ttl)
+return 1
@@ -0,0 +1,145 @@
+package ratelimiter
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+//go:embed redis_limiter.lua
+var redisLimiterSource string
+
+var redisLimiterScript = redis.NewScript(redisLimiterSource)
+
+type RedisLimiter struct {
+	keyspace Keyspace
+	client   *redis.Client
+	timeout  time.Duration
+	now      func() time.Time
+}
+
+func NewRedisLimiter(client *redis.Client, opts ...Option) *RedisLimiter {
+	o := newOptions(opts)
+	return &RedisLimiter{keyspace: o.keyspace, client: client, timeout: o.timeout, now: o.now}
+}
+
+func (l *RedisLimiter) HandleRequest(ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestContext(context.Background(), ip, token, config)
+}
+
+func (l *RedisLimiter) HandleRequestContext(ctx context.Context, ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestN(ctx, ip, token, config, 1)
+}
+
+func (l *RedisLimiter) HandleRequestN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	return l.handle(ctx, ip, token, config, cost, true)
+}
+
+func (l *RedisLimiter) peekN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	return l.handle(ctx, ip, token, config, cost, false)
+}
+
+func (l *RedisLimiter) handle(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int, charge bool) (*Decision, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+
+	limits, err := config.limits(ctx, ip, token)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := config.strategy()
+
+	evaluate := evaluation(func(key string, limit dimensionLimit, charge bool) (*Decision, error) {
+		client := newClientLimiter(limit.config.RequestesPerSecond, limit.config.BlockUserFor)
+		client.configure(limit.config)
+
+		decision, err := l.run(ctx, key, strategy, client, normalizeCost(cost), !charge)
+		if err != nil {
+			return decision, err
+		}
+		if !decision.Allowed {
+			return decision, ErrMaxRequests
+		}
+		return decision, nil
+	})
+
+	if !charge {
+		evaluate = evaluate.peeking()
+	}
+
+	decision, err := enforce(l.keyspace, config, limits, evaluate)
+
+	if err != nil && !errors.Is(err, ErrMaxRequests) {
+		return nil, fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
+	}
+
+	return decision, err
+}
+
+func (l *RedisLimiter) run(ctx context.Context, key string, strategy Strategy, client *ClientRateLimiter, cost int, dry bool) (*Decision, error) {
+	ctx, cancel := withTimeout(ctx, l.timeout)
+	defer cancel()
+
+	now := l.now()
+
+	window := client.window().Milliseconds()
+	capacity := float64(client.RequestsPerSecond)
+	rate := capacity / float64(window)
+
+	if strategy.Name() == StrategyTokenBucket {
+		capacity = float64(client.bucketCapacity())
+		rate = client.bucketRefillRate() / 1000
+	}
+
+	quotaLimit, quotaStart := 0, int64(0)
+	if client.Quota != nil {
+		quotaLimit = client.Quota.Limit
+		quotaStart = client.Quota.start(now).UnixMilli()
+	}
+
+	result, err := redisLimiterScript.Run(ctx, l.client, []string{key, l.keyspace.Key(DimensionLog, key)},
+		strategy.Name(),
+		client.RequestsPerSecond,
+		window,
+		client.BlockUserFor.Milliseconds(),
+		cost,
+		capacity,
+		rate,
+		client.ttl().Milliseconds(),
+		quotaLimit,
+		quotaStart,
+		dry,
+	).Int64Slice()
+
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{
+		Allowed:    result[0] == 1,
+		Limit:      int(capacity),
+		Remaining:  int(result[1]),
+		Reset:      now.Add(time.Duration(result[2]) * time.Millisecond),
+		RetryAfter: time.Duration(result[3]) * time.Millisecond,
+	}
+
+	if client.Quota != nil {
+		decision.QuotaLimit = client.Quota.Limit
+		decision.QuotaRemaining = int(result[4])
+		decision.QuotaReset = client.Quota.end(now)
+	}
+
+	if result[5] == 1 {
+		decision.Reset = decision.QuotaReset
+		decision.RetryAfter = decision.QuotaReset.Sub(now)
+		return decision, ErrQuotaExceeded
+	}
+
+	return decision, nil
+}
@@ -0,0 +1,166 @@
+local state_key, log_key = KEYS[1], KEYS[2]
+
+local strategy = ARGV[1]
+local limit = tonumber(ARGV[2])
+local window = tonumber(ARGV[3])
+local block = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+local capacity = tonumber(ARGV[6])
+local rate = tonumber(ARGV[7])
+local ttl = tonumber(ARGV[8])
+local quota_limit = tonumber(ARGV[9])
+local quota_start = tonumber(ARGV[10])
+local dry = ARGV[11] == '1'
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local function field(name)
+  return tonumber(redis.call('HGET', state_key, name) or '0')
+end
+
+local function write(...)
+  if not dry then
+    return redis.call(...)
+  end
+end
+
+local function free(max, used)
+  return math.max(0, math.floor(max - used))
+end
+
+local quota_used = 0
+if quota_limit > 0 and field('quota_start') == quota_start then
+  quota_used = field('quota_used')
+end
+
+local blocked_at = field('blocked_at')
+if blocked_at > 0 then
+  if now - blocked_at <= block then
+    local retry = blocked_at + block - now
+    return {0, 0, retry, retry, free(quota_limit, quota_used), 0}
+  end
+  write('HDEL', state_key, 'blocked_at')
+end
+
+if quota_limit > 0 and quota_used + cost > quota_limit then
+  return {0, 0, 0, 0, free(quota_limit, quota_used), 1}
+end
+
+local allowed = false
+local remaining = 0
+local reset = 0
+
+if strategy == 'fixed-window' then
+  local start = field('window_start')
+  local count = field('count')
+  if now - start >= window then
+    start = now
+    count = 0
+  end
+  if count + cost <= limit then
+    allowed = true
+    count = count + cost
+  end
+  write('HSET', state_key, 'window_start', start, 'count', count)
+  remaining = free(limit, count)
+  reset = start + window - now
+elseif strategy == 'sliding-window-log' then
+  write('ZREMRANGEBYSCORE', log_key, '-inf', now - window)
+  local count = redis.call('ZCOUNT', log_key, '(' .. (now - window), '+inf')
+  if count + cost <= limit then
+    allowed = true
+    if not dry then
+      local seq = redis.call('HINCRBY', state_key, 'seq', cost)
+      for i = 1, cost do
+        redis.call('ZADD', log_key, now, seq - cost + i)
+      end
+    end
+    count = count + cost
+  end
+  write('PEXPIRE', log_key, window)
+  remaining = free(limit, count)
+  local oldest = redis.call('ZRANGEBYSCORE', log_key, '(' .. (now - window), '+inf', 'WITHSCORES', 'LIMIT', 0, 1)
+  if oldest[2] then
+    reset = tonumber(oldest[2]) + window - now
+  elseif allowed then
+    reset = window
+  end
+elseif strategy == 'sliding-window-counter' then
+  local start = field('window_start')
+  local current = field('count')
+  local previous = field('previous')
+  local aligned = now - (now % window)
+  if aligned ~= start then
+    if aligned - start == window then
+      previous = current
+    else
+      previous = 0
+    end
+    current = 0
+    start = aligned
+  end
+  local estimated = previous * (window - (now - start)) / window + current
+  if estimated + cost <= limit then
+    allowed = true
+    current = current + cost
+    estimated = estimated + cost
+  end
+  write('HSET', state_key, 'window_start', start, 'count', current, 'previous', previous)
+  remaining = free(limit, estimated)
+  reset = start + window - now
+elseif strategy == 'token-bucket' then
+  local tokens = capacity
+  local stored = redis.call('HGET', state_key, 'tokens')
+  if stored then
+    tokens = math.min(capacity, tonumber(stored) + math.max(0, now - field('last_refill')) * rate)
+  end
+  if tokens >= cost then
+    allowed = true
+    tokens = tokens - cost
+  end
+  write('HSET', state_key, 'tokens', tokens, 'last_refill', now)
+  remaining = free(tokens, 0)
+  if remaining < capacity then
+    reset = (remaining + 1 - tokens) / rate
+  end
+elseif strategy == 'leaky-bucket' then
+  local level = 0
+  local stored = redis.call('HGET', state_key, 'level')
+  if stored then
+    level = math.max(0, tonumber(stored) - math.max(0, now - field('last_leak')) * rate)
+  end
+  if level + cost <= capacity then
+    allowed = true
+    level = level + cost
+  end
+  write('HSET', state_key, 'level', level, 'last_leak', now)
+  remaining = free(capacity, level)
+  if remaining < capacity then
+    reset = (level - (capacity - remaining - 1)) / rate
+  end
+else
+  return redis.error_reply('unknown rate limiter strategy ' .. strategy)
+end
+
+reset = math.max(0, math.ceil(reset))
+local retry = 0
+
+if not allowed then
+  if block > 0 and not dry then
+    redis.call('HSET', state_key, 'blocked_at', now)
+    reset = math.max(reset, block)
+  end
+  remaining = 0
+  retry = reset
+end
+if allowed and quota_limit > 0 then
+  quota_used = quota_used + cost
+  write('HSET', state_key, 'quota_start', quota_start, 'quota_used', quota_used)
+end
+write('PEXPIRE', state_key, ttl)
+
+if allowed then
+  return {1, remaining, reset, retry, free(quota_limit, quota_used), 0}
+end
+return {0, remaining, reset, retry, free(quota_limit, quota_used), 0}
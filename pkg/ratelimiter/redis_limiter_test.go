@@ -0,0 +1,152 @@
+package ratelimiter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestRedisLimiter(t *testing.T) {
+	t.Run("should allow up to the limit and deny the next request", func(t *testing.T) {
+		for _, name := range []string{
+			StrategyFixedWindow,
+			StrategySlidingWindowLog,
+			StrategySlidingWindowCounter,
+			StrategyTokenBucket,
+			StrategyLeakyBucket,
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, client := newTestRedis(t)
+				limiter := NewRedisLimiter(client)
+
+				strategy, err := NewStrategy(name)
+				assert.NoError(t, err)
+
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(5, 10*time.Second), nil)
+				config.Strategy = strategy
+
+				for i := 0; i < 5; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+			})
+		}
+	})
+
+	t.Run("should use the token config when a token is provided", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+		config := NewRateLimiterConfig(
+			NewRateLimiterConfigByIP(10, 10*time.Second),
+			NewRateLimiterConfigByToken(2, 10*time.Second, "API_KEY"),
+		)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc1234", config)))
+		}
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc1234", config)), ErrMaxRequests)
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+
+	t.Run("should keep the user blocked until the blocking period has expired", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 30*time.Second), nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+
+		server.SetTime(time.Date(2024, 1, 1, 0, 0, 20, 0, time.UTC))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+
+		server.SetTime(time.Date(2024, 1, 1, 0, 0, 31, 0, time.UTC))
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+
+	t.Run("should not share keys between a sliding log and a token ending in :log", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		config := NewRateLimiterConfig(nil, NewRateLimiterConfigByToken(5, 0, "API_KEY"))
+		config.Strategy = NewSlidingWindowLog()
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc", config)))
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc:log", config)))
+		assert.True(t, server.Exists("ratelimiter:log:ratelimiter:token:abc"))
+	})
+
+	t.Run("should keep counting the window when denied requests are not blocked", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		configByIP := NewRateLimiterConfigByIP(5, 0)
+		configByIP.Window = time.Minute
+		config := NewRateLimiterConfig(configByIP, nil)
+
+		allowed := 0
+		for i := 0; i < 100; i++ {
+			if requestErr(limiter.HandleRequest("127.0.0.1", "", config)) == nil {
+				allowed++
+			}
+		}
+		assert.Equal(t, 5, allowed)
+		assert.Equal(t, "5", server.HGet("ratelimiter:ip:127.0.0.1", "count"))
+		assert.Equal(t, "", server.HGet("ratelimiter:ip:127.0.0.1", "blocked_at"))
+
+		server.SetTime(time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+		assert.Equal(t, "5", server.HGet("ratelimiter:ip:127.0.0.1", "count"))
+
+		server.SetTime(time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+
+	t.Run("should not over-admit concurrent requests", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(50, 10*time.Second), nil)
+
+		var mux sync.Mutex
+		var wg sync.WaitGroup
+		allowed := 0
+
+		for replica := 0; replica < 6; replica++ {
+			limiter := NewRedisLimiter(client)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if requestErr(limiter.HandleRequest("127.0.0.1", "", config)) == nil {
+						mux.Lock()
+						allowed++
+						mux.Unlock()
+					}
+				}()
+			}
+		}
+		wg.Wait()
+
+		assert.Equal(t, 50, allowed)
+	})
+
+	t.Run("should return an error when redis is unavailable", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		server.Close()
+
+		limiter := NewRedisLimiter(client)
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 30*time.Second), nil)
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrGettingRateLimiterData)
+	})
+}
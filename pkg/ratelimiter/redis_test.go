@@ -0,0 +1,102 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type expiringDatasource struct {
+	*RedisDatasource
+	server *miniredis.Miniredis
+}
+
+func (d *expiringDatasource) HasContext(ctx context.Context, key string) (bool, error) {
+	found, err := d.RedisDatasource.HasContext(ctx, key)
+	d.server.Del(key)
+	return found, err
+}
+
+func (d *expiringDatasource) GetContext(ctx context.Context, key string) (*ClientRateLimiter, error) {
+	d.server.Del(key)
+	return d.RedisDatasource.GetContext(ctx, key)
+}
+
+func TestRedisDatasource(t *testing.T) {
+	t.Run("should expire the client with the window length when it is not blocked", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		datasource := NewRedisDatasource(client)
+
+		clientLimiter := newClientLimiter(10, 5*time.Second)
+		clientLimiter.Window = time.Minute
+
+		assert.NoError(t, datasource.Set("127.0.0.1", clientLimiter))
+		assert.Equal(t, 2*time.Minute, server.TTL("127.0.0.1"))
+	})
+
+	t.Run("should keep the client until the blocking period has expired", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		datasource := NewRedisDatasource(client)
+
+		clientLimiter := newClientLimiter(10, 10*time.Minute)
+
+		assert.NoError(t, datasource.Set("127.0.0.1", clientLimiter))
+		assert.Equal(t, 10*time.Minute, server.TTL("127.0.0.1"))
+	})
+
+	t.Run("should forget the client state once its key expires", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		limiter := NewRateLimiter(NewRedisDatasource(client), NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 30*time.Second), nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+
+		server.FastForward(31 * time.Second)
+
+		assert.False(t, server.Exists("ratelimiter:ip:127.0.0.1"))
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+
+	t.Run("should report a missing key as not found", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		datasource := NewRedisDatasource(client)
+
+		clientLimiter, err := datasource.Get("127.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Nil(t, clientLimiter)
+	})
+
+	t.Run("should create a new client when the key expires before it is read", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		datasource := &expiringDatasource{RedisDatasource: NewRedisDatasource(client), server: server}
+		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 30*time.Second), nil)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+
+	t.Run("should not sweep the keys of the database", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		assert.NoError(t, server.Set("unrelated", "value"))
+
+		timeSleeper := &TimeSleeperMock{}
+		timeSleeper.On("Sleep", 1*time.Second).Return()
+
+		limiter := NewRateLimiter(NewRedisDatasource(client), timeSleeper)
+		defer limiter.Stop()
+
+		limiter.clear()
+
+		assert.Equal(t, 0, server.CommandCount())
+	})
+}
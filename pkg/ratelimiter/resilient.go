@@ -0,0 +1,105 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrCircuitOpen          = errors.New("the datasource circuit breaker is open")
+	ErrUnknownFailurePolicy = errors.New("unknown failure policy")
+)
+
+type FailurePolicy string
+
+const (
+	FailClosed FailurePolicy = "closed"
+	FailOpen   FailurePolicy = "open"
+	FailLocal  FailurePolicy = "local"
+)
+
+func ParseFailurePolicy(policy string) (FailurePolicy, error) {
+	switch FailurePolicy(policy) {
+	case "", FailClosed:
+		return FailClosed, nil
+	case FailOpen:
+		return FailOpen, nil
+	case FailLocal:
+		return FailLocal, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFailurePolicy, policy)
+}
+
+type ResilientLimiter struct {
+	limiter  Limiter
+	fallback Limiter
+	breaker  *CircuitBreaker
+	policy   FailurePolicy
+}
+
+func NewResilientLimiter(limiter Limiter, breaker *CircuitBreaker, policy FailurePolicy, opts ...Option) *ResilientLimiter {
+	resilient := &ResilientLimiter{limiter: limiter, breaker: breaker, policy: policy}
+
+	if policy == FailLocal {
+		resilient.fallback = NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), opts...)
+	}
+
+	return resilient
+}
+
+func (l *ResilientLimiter) HandleRequest(ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestContext(context.Background(), ip, token, config)
+}
+
+func (l *ResilientLimiter) HandleRequestContext(ctx context.Context, ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestN(ctx, ip, token, config, 1)
+}
+
+func (l *ResilientLimiter) HandleRequestN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	if !l.breaker.Allow() {
+		return l.fail(ctx, ip, token, config, cost, ErrCircuitOpen)
+	}
+
+	decision, err := l.limiter.HandleRequestN(ctx, ip, token, config, cost)
+
+	if err == nil || !errors.Is(err, ErrGettingRateLimiterData) || isConfigError(err) {
+		l.breaker.Success()
+		return decision, err
+	}
+
+	if ctx.Err() != nil {
+		l.breaker.abort()
+	} else {
+		l.breaker.Failure()
+	}
+
+	return l.fail(ctx, ip, token, config, cost, err)
+}
+
+func (l *ResilientLimiter) peekN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	if l.breaker.IsOpen() {
+		if fallback, ok := l.fallback.(peeker); ok {
+			return fallback.peekN(ctx, ip, token, config, cost)
+		}
+		return nil, ErrCircuitOpen
+	}
+	if limiter, ok := l.limiter.(peeker); ok {
+		return limiter.peekN(ctx, ip, token, config, cost)
+	}
+	return nil, ErrGettingRateLimiterData
+}
+
+func (l *ResilientLimiter) fail(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int, err error) (*Decision, error) {
+	switch l.policy {
+	case FailOpen:
+		return &Decision{Allowed: true}, nil
+	case FailLocal:
+		return l.fallback.HandleRequestN(ctx, ip, token, config, cost)
+	}
+
+	if errors.Is(err, ErrGettingRateLimiterData) {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: %w", err, ErrGettingRateLimiterData)
+}
@@ -0,0 +1,141 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingLimiter struct {
+	calls int
+	peeks int
+	err   error
+}
+
+func (l *failingLimiter) HandleRequest(ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestContext(context.Background(), ip, token, config)
+}
+
+func (l *failingLimiter) HandleRequestContext(ctx context.Context, ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestN(ctx, ip, token, config, 1)
+}
+
+func (l *failingLimiter) HandleRequestN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	l.calls++
+	return nil, l.err
+}
+
+func (l *failingLimiter) peekN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	l.peeks++
+	return nil, l.err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("should open after the failure threshold and probe once the cooldown has elapsed", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		breaker := NewCircuitBreaker(2, 30*time.Second)
+		breaker.now = func() time.Time { return now }
+
+		breaker.Failure()
+		assert.True(t, breaker.Allow())
+		breaker.Failure()
+		assert.False(t, breaker.Allow())
+
+		now = now.Add(31 * time.Second)
+		assert.True(t, breaker.Allow())
+		assert.False(t, breaker.Allow())
+
+		breaker.Success()
+		assert.False(t, breaker.IsOpen())
+		assert.True(t, breaker.Allow())
+	})
+
+	t.Run("should open again when the probe fails", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		breaker := NewCircuitBreaker(1, 30*time.Second)
+		breaker.now = func() time.Time { return now }
+
+		breaker.Failure()
+		now = now.Add(31 * time.Second)
+		assert.True(t, breaker.Allow())
+
+		breaker.Failure()
+		assert.False(t, breaker.Allow())
+	})
+}
+
+func TestResilientLimiter(t *testing.T) {
+	config := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, 10*time.Second), nil)
+	datasourceErr := fmt.Errorf("%w: %w", errors.New("connection refused"), ErrGettingRateLimiterData)
+
+	t.Run("should return the datasource error when failing closed", func(t *testing.T) {
+		limiter := NewResilientLimiter(&failingLimiter{err: datasourceErr}, NewCircuitBreaker(5, time.Minute), FailClosed)
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrGettingRateLimiterData)
+	})
+
+	t.Run("should allow the request when failing open", func(t *testing.T) {
+		limiter := NewResilientLimiter(&failingLimiter{err: datasourceErr}, NewCircuitBreaker(5, time.Minute), FailOpen)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+
+	t.Run("should keep enforcing the limit with a local limiter", func(t *testing.T) {
+		limiter := NewResilientLimiter(&failingLimiter{err: datasourceErr}, NewCircuitBreaker(5, time.Minute), FailLocal)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+	})
+
+	t.Run("should not call the datasource while the circuit is open", func(t *testing.T) {
+		primary := &failingLimiter{err: datasourceErr}
+		limiter := NewResilientLimiter(primary, NewCircuitBreaker(2, time.Minute), FailClosed)
+
+		for i := 0; i < 5; i++ {
+			limiter.HandleRequest("127.0.0.1", "", config)
+		}
+
+		assert.Equal(t, 2, primary.calls)
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrCircuitOpen)
+	})
+
+	t.Run("should not count rejected requests as failures", func(t *testing.T) {
+		primary := &failingLimiter{err: ErrMaxRequests}
+		breaker := NewCircuitBreaker(1, time.Minute)
+		limiter := NewResilientLimiter(primary, breaker, FailOpen)
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrMaxRequests)
+		assert.False(t, breaker.IsOpen())
+	})
+
+	t.Run("should not count config and registry errors as failures", func(t *testing.T) {
+		primary := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer primary.Stop()
+
+		breaker := NewCircuitBreaker(1, time.Minute)
+		limiter := NewResilientLimiter(primary, breaker, FailOpen)
+
+		tokenConfig := NewRateLimiterConfigByToken(2, 10*time.Second, "API_KEY")
+		tokenConfig.Registry = failingRegistry{}
+
+		for i := 0; i < 3; i++ {
+			assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "abc", NewRateLimiterConfig(nil, tokenConfig))), ErrTokenRegistry)
+			assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", nil)), ErrNilConfig)
+		}
+		assert.False(t, breaker.IsOpen())
+	})
+
+	t.Run("should fail open when redis is unavailable", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		server.Close()
+
+		limiter := NewResilientLimiter(NewRedisLimiter(client), NewCircuitBreaker(1, time.Minute), FailOpen)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+	})
+}
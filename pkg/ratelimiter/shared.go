@@ -0,0 +1,51 @@
+package ratelimiter
+
+import "time"
+
+const globalKey = "all"
+
+type RateLimiterConfigGlobal struct {
+	BaseLimiterConfig
+}
+
+type RateLimiterConfigByBucket struct {
+	BaseLimiterConfig
+	Name string
+}
+
+func NewRateLimiterConfigGlobal(requestesPerSecond int, blockUserFor time.Duration) *RateLimiterConfigGlobal {
+	return &RateLimiterConfigGlobal{
+		BaseLimiterConfig: BaseLimiterConfig{
+			RequestesPerSecond: requestesPerSecond,
+			BlockUserFor:       blockUserFor,
+		},
+	}
+}
+
+func NewRateLimiterConfigByBucket(requestesPerSecond int, blockUserFor time.Duration, name string) *RateLimiterConfigByBucket {
+	return &RateLimiterConfigByBucket{
+		BaseLimiterConfig: BaseLimiterConfig{
+			RequestesPerSecond: requestesPerSecond,
+			BlockUserFor:       blockUserFor,
+		},
+		Name: name,
+	}
+}
+
+func (c *RateLimiterConfig) hasSharedLimits() bool {
+	return c.ConfigGlobal != nil || len(c.Buckets) > 0
+}
+
+func (c *RateLimiterConfig) sharedLimits() []dimensionLimit {
+	var limits []dimensionLimit
+
+	if c.ConfigGlobal != nil {
+		limits = append(limits, dimensionLimit{DimensionGlobal, globalKey, &c.ConfigGlobal.BaseLimiterConfig})
+	}
+
+	for _, bucket := range c.Buckets {
+		limits = append(limits, dimensionLimit{DimensionBucket, bucket.Name, &bucket.BaseLimiterConfig})
+	}
+
+	return limits
+}
@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedLimits(t *testing.T) {
+	for name, newLimiter := range testLimiters() {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should cap the throughput across all clients", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+				config.ConfigGlobal = NewRateLimiterConfigGlobal(3, 0)
+
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.2", "", config)))
+
+				decision, err := limiter.HandleRequest("10.0.0.3", "", config)
+				assert.NoError(t, err)
+				assert.Equal(t, 3, decision.Limit)
+				assert.Equal(t, 0, decision.Remaining)
+
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.4", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should reopen the global cap once its window passes", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(nil, nil)
+				config.ConfigGlobal = NewRateLimiterConfigGlobal(3, 0)
+
+				for i := 0; i < 3; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.2", "", config)), ErrMaxRequests)
+
+				limiter.Advance(time.Second)
+
+				for i := 0; i < 3; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.2", "", config)))
+				}
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.3", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should not charge the client when the global limit denies", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(NewRateLimiterConfigByIP(4, 0), nil)
+				config.ConfigByIP.Window = time.Hour
+				config.ConfigGlobal = NewRateLimiterConfigGlobal(3, 0)
+
+				for i := 0; i < 3; i++ {
+					assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+				}
+				for i := 0; i < 5; i++ {
+					assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)), ErrMaxRequests)
+				}
+
+				limiter.Advance(time.Second)
+
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should enforce a global limit without client limits", func(t *testing.T) {
+				limiter := newLimiter(t)
+				config := NewRateLimiterConfig(nil, nil)
+				config.ConfigGlobal = NewRateLimiterConfigGlobal(1, 0)
+
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.2", "", config)), ErrMaxRequests)
+			})
+
+			t.Run("should share a named bucket across routes", func(t *testing.T) {
+				limiter := newLimiter(t)
+
+				database := NewRateLimiterConfigByBucket(2, 0, "database")
+
+				orders := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+				orders.Scope = "orders"
+				orders.Buckets = []*RateLimiterConfigByBucket{database}
+
+				reports := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+				reports.Scope = "reports"
+				reports.Buckets = []*RateLimiterConfigByBucket{database}
+
+				health := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+				health.Scope = "health"
+
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", orders)))
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.2", "", reports)))
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.3", "", orders)), ErrMaxRequests)
+				assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.3", "", reports)), ErrMaxRequests)
+				assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.3", "", health)))
+			})
+		})
+	}
+
+	t.Run("should not scope the shared keys by route", func(t *testing.T) {
+		datasource := NewInMemoryDatasource()
+		limiter := NewRateLimiter(datasource, NewTimeSleeper())
+		defer limiter.Stop()
+
+		config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 10*time.Second), nil)
+		config.Scope = "orders"
+		config.ConfigGlobal = NewRateLimiterConfigGlobal(10, 0)
+		config.Buckets = []*RateLimiterConfigByBucket{NewRateLimiterConfigByBucket(10, 0, "database")}
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+		assert.True(t, datasource.Has("ratelimiter:route:orders:ip:10.0.0.1"))
+		assert.True(t, datasource.Has("ratelimiter:global:all"))
+		assert.True(t, datasource.Has("ratelimiter:bucket:database"))
+	})
+}
@@ -0,0 +1,101 @@
+package ratelimiter
+
+import "time"
+
+type SlidingWindowLog struct{}
+
+func NewSlidingWindowLog() *SlidingWindowLog {
+	return &SlidingWindowLog{}
+}
+
+func (s *SlidingWindowLog) Name() string {
+	return StrategySlidingWindowLog
+}
+
+func (s *SlidingWindowLog) AllowN(client *ClientRateLimiter, now time.Time, n int) bool {
+	client.Log = s.inWindow(client, now)
+
+	if len(client.Log)+n > client.RequestsPerSecond {
+		return false
+	}
+
+	for i := 0; i < n; i++ {
+		client.Log = append(client.Log, now)
+	}
+	return true
+}
+
+func (s *SlidingWindowLog) Status(client *ClientRateLimiter, now time.Time) (int, int, time.Time) {
+	client.Log = s.inWindow(client, now)
+	if len(client.Log) == 0 {
+		return client.RequestsPerSecond, client.RequestsPerSecond, now
+	}
+	return client.RequestsPerSecond, remaining(client.RequestsPerSecond, float64(len(client.Log))), client.Log[0].Add(client.window())
+}
+
+func (s *SlidingWindowLog) inWindow(client *ClientRateLimiter, now time.Time) []time.Time {
+	threshold := now.Add(-client.window())
+
+	kept := client.Log[:0]
+	for _, at := range client.Log {
+		if at.After(threshold) {
+			kept = append(kept, at)
+		}
+	}
+	return kept
+}
+
+type SlidingWindowCounter struct{}
+
+func NewSlidingWindowCounter() *SlidingWindowCounter {
+	return &SlidingWindowCounter{}
+}
+
+func (s *SlidingWindowCounter) Name() string {
+	return StrategySlidingWindowCounter
+}
+
+func (s *SlidingWindowCounter) AllowN(client *ClientRateLimiter, now time.Time, n int) bool {
+	s.advance(client, now)
+
+	if s.estimate(client, now)+float64(n) > float64(client.RequestsPerSecond) {
+		return false
+	}
+
+	client.WindowRequests += n
+	return true
+}
+
+func (s *SlidingWindowCounter) Status(client *ClientRateLimiter, now time.Time) (int, int, time.Time) {
+	s.advance(client, now)
+	return client.RequestsPerSecond, remaining(client.RequestsPerSecond, s.estimate(client, now)), client.WindowStart.Add(client.window())
+}
+
+func (s *SlidingWindowCounter) estimate(client *ClientRateLimiter, now time.Time) float64 {
+	window := client.window()
+	elapsed := now.Sub(client.WindowStart)
+	previousWeight := float64(window-elapsed) / float64(window)
+	return float64(client.PreviousWindowRequests)*previousWeight + float64(client.WindowRequests)
+}
+
+func (s *SlidingWindowCounter) advance(client *ClientRateLimiter, now time.Time) {
+	window := client.window()
+
+	if client.WindowStart.IsZero() {
+		client.WindowStart = now.Truncate(window)
+		return
+	}
+
+	elapsed := now.Sub(client.WindowStart)
+	if elapsed < window {
+		return
+	}
+
+	if elapsed < 2*window {
+		client.PreviousWindowRequests = client.WindowRequests
+	} else {
+		client.PreviousWindowRequests = 0
+	}
+	client.WindowRequests = 0
+	client.WindowStart = now.Truncate(window)
+}
@@ -0,0 +1,47 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrUnknownStrategy = errors.New("unknown rate limiter strategy")
+
+const (
+	StrategyFixedWindow          = "fixed-window"
+	StrategySlidingWindowLog     = "sliding-window-log"
+	StrategySlidingWindowCounter = "sliding-window-counter"
+	StrategyTokenBucket          = "token-bucket"
+	StrategyLeakyBucket          = "leaky-bucket"
+)
+
+type Strategy interface {
+	Name() string
+	AllowN(client *ClientRateLimiter, now time.Time, n int) bool
+	Status(client *ClientRateLimiter, now time.Time) (limit, remaining int, reset time.Time)
+}
+
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case "", StrategyFixedWindow:
+		return NewFixedWindow(), nil
+	case StrategySlidingWindowLog:
+		return NewSlidingWindowLog(), nil
+	case StrategySlidingWindowCounter:
+		return NewSlidingWindowCounter(), nil
+	case StrategyTokenBucket:
+		return NewTokenBucket(), nil
+	case StrategyLeakyBucket:
+		return NewLeakyBucket(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+}
+
+func remaining(limit int, used float64) int {
+	if free := int(math.Floor(float64(limit) - used)); free > 0 {
+		return free
+	}
+	return 0
+}
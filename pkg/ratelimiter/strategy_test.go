@@ -0,0 +1,256 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonDatasource struct {
+	clients map[string][]byte
+	mux     sync.Mutex
+}
+
+func newJSONDatasource() *jsonDatasource {
+	return &jsonDatasource{clients: make(map[string][]byte)}
+}
+
+func (d *jsonDatasource) Set(key string, data *ClientRateLimiter) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	d.clients[key] = jsonData
+	return nil
+}
+
+func (d *jsonDatasource) Get(key string) (*ClientRateLimiter, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	data, found := d.clients[key]
+	if !found {
+		return nil, nil
+	}
+	var client *ClientRateLimiter
+	if err := json.Unmarshal(data, &client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (d *jsonDatasource) Has(key string) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	_, found := d.clients[key]
+	return found
+}
+
+func TestStrategies(t *testing.T) {
+	t.Run("NewStrategy", func(t *testing.T) {
+		t.Run("should default to the fixed window strategy", func(t *testing.T) {
+			strategy, err := NewStrategy("")
+			assert.NoError(t, err)
+			assert.Equal(t, StrategyFixedWindow, strategy.Name())
+		})
+		t.Run("should return an error when the strategy is unknown", func(t *testing.T) {
+			strategy, err := NewStrategy("unknown")
+			assert.Nil(t, strategy)
+			assert.ErrorIs(t, err, ErrUnknownStrategy)
+		})
+	})
+
+	t.Run("should allow up to the limit and deny the next request", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for _, name := range []string{
+			StrategyFixedWindow,
+			StrategySlidingWindowLog,
+			StrategySlidingWindowCounter,
+			StrategyTokenBucket,
+			StrategyLeakyBucket,
+		} {
+			t.Run(name, func(t *testing.T) {
+				strategy, err := NewStrategy(name)
+				assert.NoError(t, err)
+
+				client := newClientLimiter(5, 10*time.Second)
+
+				for i := 0; i < 5; i++ {
+					assert.True(t, strategy.AllowN(client, now, 1))
+				}
+				assert.False(t, strategy.AllowN(client, now, 1))
+			})
+		}
+	})
+
+	t.Run("FixedWindow", func(t *testing.T) {
+		t.Run("should reset the requests once the client window has elapsed", func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+			strategy := NewFixedWindow()
+			client := newClientLimiter(100, 10*time.Second)
+			client.Window = time.Minute
+
+			assert.True(t, strategy.AllowN(client, now, 100))
+			assert.False(t, strategy.AllowN(client, now.Add(59*time.Second), 1))
+			assert.True(t, strategy.AllowN(client, now.Add(time.Minute), 100))
+		})
+		t.Run("should honor the window of each client", func(t *testing.T) {
+			clock := newTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(clock.Now))
+			defer limiter.Stop()
+
+			configByIP := NewRateLimiterConfigByIP(1, 0)
+			configByIP.Window = time.Hour
+			config := NewRateLimiterConfig(configByIP, nil)
+
+			assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+			clock.Advance(30 * time.Minute)
+			assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.2", "", config)))
+
+			clock.Advance(31 * time.Minute)
+			assert.NoError(t, requestErr(limiter.HandleRequest("10.0.0.1", "", config)))
+			assert.ErrorIs(t, requestErr(limiter.HandleRequest("10.0.0.2", "", config)), ErrMaxRequests)
+		})
+	})
+
+	t.Run("SlidingWindowLog", func(t *testing.T) {
+		t.Run("should allow requests again once the old ones leave the window", func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			strategy := NewSlidingWindowLog()
+			client := newClientLimiter(2, 10*time.Second)
+
+			assert.True(t, strategy.AllowN(client, now, 1))
+			assert.True(t, strategy.AllowN(client, now.Add(500*time.Millisecond), 1))
+			assert.False(t, strategy.AllowN(client, now.Add(900*time.Millisecond), 1))
+			assert.True(t, strategy.AllowN(client, now.Add(1100*time.Millisecond), 1))
+		})
+	})
+
+	t.Run("SlidingWindowCounter", func(t *testing.T) {
+		t.Run("should weight the previous window count", func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			strategy := NewSlidingWindowCounter()
+			client := newClientLimiter(10, 10*time.Second)
+
+			for i := 0; i < 10; i++ {
+				assert.True(t, strategy.AllowN(client, start.Add(900*time.Millisecond), 1))
+			}
+
+			allowed := 0
+			for i := 0; i < 10; i++ {
+				if strategy.AllowN(client, start.Add(1100*time.Millisecond), 1) {
+					allowed++
+				}
+			}
+			assert.Equal(t, 1, allowed)
+		})
+		t.Run("should not allow twice the limit across a window boundary on any datasource", func(t *testing.T) {
+			ip := "127.0.0.1"
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			for name, datasource := range map[string]Datasource{
+				"in memory":  NewInMemoryDatasource(),
+				"serialized": newJSONDatasource(),
+			} {
+				t.Run(name, func(t *testing.T) {
+					clock := newTestClock(start)
+					limiter := NewRateLimiter(datasource, NewTimeSleeper(), WithClock(clock.Now))
+					defer limiter.Stop()
+
+					config := NewRateLimiterConfig(NewRateLimiterConfigByIP(10, 0), nil)
+					config.Strategy = NewSlidingWindowCounter()
+
+					allowed := 0
+					for _, at := range []time.Duration{900 * time.Millisecond, 1100 * time.Millisecond} {
+						clock.Set(start.Add(at))
+						for i := 0; i < 10; i++ {
+							if requestErr(limiter.HandleRequest(ip, "", config)) == nil {
+								allowed++
+							}
+						}
+					}
+
+					assert.Equal(t, 11, allowed)
+				})
+			}
+		})
+	})
+
+	t.Run("TokenBucket", func(t *testing.T) {
+		t.Run("should refill the tokens based on the elapsed time", func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			strategy := NewTokenBucket()
+			client := newClientLimiter(10, 10*time.Second)
+
+			assert.True(t, strategy.AllowN(client, now, 10))
+			assert.False(t, strategy.AllowN(client, now, 1))
+			assert.True(t, strategy.AllowN(client, now.Add(200*time.Millisecond), 2))
+			assert.False(t, strategy.AllowN(client, now.Add(200*time.Millisecond), 1))
+		})
+		t.Run("should use the burst and refill rate independently of the requests per second", func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			strategy := NewTokenBucket()
+			client := newClientLimiter(10, 10*time.Second)
+			client.Burst = 30
+			client.RefillRate = 2
+
+			assert.True(t, strategy.AllowN(client, now, 30))
+			assert.False(t, strategy.AllowN(client, now, 1))
+			assert.False(t, strategy.AllowN(client, now.Add(400*time.Millisecond), 1))
+			assert.True(t, strategy.AllowN(client, now.Add(500*time.Millisecond), 1))
+			assert.False(t, strategy.AllowN(client, now.Add(500*time.Millisecond), 1))
+		})
+		t.Run("should keep the token state when the client is stored in the datasource", func(t *testing.T) {
+			ip := "127.0.0.1"
+
+			limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+			defer limiter.Stop()
+			configByIP := NewRateLimiterConfigByIP(1, 10*time.Second)
+			configByIP.Burst = 3
+			configByIP.RefillRate = 0.1
+			config := NewRateLimiterConfig(configByIP, nil)
+			config.Strategy = NewTokenBucket()
+
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, requestErr(limiter.HandleRequest(ip, "", config)))
+			}
+
+			assert.ErrorIs(t, requestErr(limiter.HandleRequest(ip, "", config)), ErrMaxRequests)
+		})
+	})
+
+	t.Run("LeakyBucket", func(t *testing.T) {
+		t.Run("should leak the bucket based on the elapsed time", func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			strategy := NewLeakyBucket()
+			client := newClientLimiter(10, 10*time.Second)
+
+			assert.True(t, strategy.AllowN(client, now, 10))
+			assert.False(t, strategy.AllowN(client, now, 1))
+			assert.True(t, strategy.AllowN(client, now.Add(500*time.Millisecond), 5))
+			assert.False(t, strategy.AllowN(client, now.Add(500*time.Millisecond), 1))
+		})
+	})
+
+	t.Run("should block the user when the configured strategy denies the request", func(t *testing.T) {
+		ip := "127.0.0.1"
+
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+		config := NewRateLimiterConfig(
+			NewRateLimiterConfigByIP(3, 10*time.Second), nil,
+		)
+		config.Strategy = NewTokenBucket()
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest(ip, "", config)))
+		}
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest(ip, "", config)), ErrMaxRequests)
+	})
+}
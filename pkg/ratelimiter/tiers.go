@@ -0,0 +1,191 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownTier   = errors.New("unknown tier")
+	ErrTokenRegistry = errors.New("error looking up the token tier")
+)
+
+type Tier struct {
+	Name string
+	BaseLimiterConfig
+}
+
+type TokenRegistry interface {
+	Lookup(ctx context.Context, token string) (*Tier, error)
+}
+
+type StaticTokenRegistry struct {
+	tiers    map[string]*Tier
+	tokens   map[string]string
+	prefixes map[string]string
+	mux      sync.RWMutex
+}
+
+func NewTokenRegistry(tiers ...*Tier) *StaticTokenRegistry {
+	registry := &StaticTokenRegistry{
+		tiers:    make(map[string]*Tier),
+		tokens:   make(map[string]string),
+		prefixes: make(map[string]string),
+	}
+	for _, tier := range tiers {
+		registry.tiers[tier.Name] = tier
+	}
+	return registry
+}
+
+func (r *StaticTokenRegistry) Assign(token, tier string) error {
+	return r.assign(r.tokens, token, tier)
+}
+
+func (r *StaticTokenRegistry) AssignPrefix(prefix, tier string) error {
+	return r.assign(r.prefixes, prefix, tier)
+}
+
+func (r *StaticTokenRegistry) assign(target map[string]string, key, tier string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, found := r.tiers[tier]; !found {
+		return fmt.Errorf("%w: %s", ErrUnknownTier, tier)
+	}
+	target[key] = tier
+	return nil
+}
+
+func (r *StaticTokenRegistry) Lookup(ctx context.Context, token string) (*Tier, error) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if tier, found := r.tokens[token]; found {
+		return r.tiers[tier], nil
+	}
+
+	match := ""
+	for prefix := range r.prefixes {
+		if strings.HasPrefix(token, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match != "" {
+		return r.tiers[r.prefixes[match]], nil
+	}
+
+	return nil, nil
+}
+
+type quotaFile struct {
+	Limit    int    `json:"limit"`
+	Period   string `json:"period"`
+	Timezone string `json:"timezone"`
+}
+
+type tierFile struct {
+	RequestsPerSecond int        `json:"requestsPerSecond"`
+	BlockUserFor      string     `json:"blockUserFor"`
+	Window            string     `json:"window"`
+	Burst             int        `json:"burst"`
+	RefillRate        float64    `json:"refillRate"`
+	Quota             *quotaFile `json:"quota"`
+}
+
+type registryFile struct {
+	Tiers    map[string]tierFile `json:"tiers"`
+	Tokens   map[string]string   `json:"tokens"`
+	Prefixes map[string]string   `json:"prefixes"`
+}
+
+func LoadTokenRegistry(path string) (*StaticTokenRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	registry := NewTokenRegistry()
+	for name, tier := range file.Tiers {
+		blockUserFor, err := parseOptionalDuration(tier.BlockUserFor)
+		if err != nil {
+			return nil, fmt.Errorf("tier %s: %w", name, err)
+		}
+		window, err := parseOptionalDuration(tier.Window)
+		if err != nil {
+			return nil, fmt.Errorf("tier %s: %w", name, err)
+		}
+		quota, err := tier.Quota.config()
+		if err != nil {
+			return nil, fmt.Errorf("tier %s: %w", name, err)
+		}
+		registry.tiers[name] = &Tier{
+			Name: name,
+			BaseLimiterConfig: BaseLimiterConfig{
+				RequestesPerSecond: tier.RequestsPerSecond,
+				BlockUserFor:       blockUserFor,
+				Window:             window,
+				Burst:              tier.Burst,
+				RefillRate:         tier.RefillRate,
+				Quota:              quota,
+			},
+		}
+	}
+
+	for token, tier := range file.Tokens {
+		if err := registry.Assign(token, tier); err != nil {
+			return nil, err
+		}
+	}
+	for prefix, tier := range file.Prefixes {
+		if err := registry.AssignPrefix(prefix, tier); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+func (f *quotaFile) config() (*QuotaConfig, error) {
+	if f == nil {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(f.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuotaConfig(f.Limit, QuotaPeriod(f.Period), location)
+}
+
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func (c *RateLimiterConfigByToken) limitsFor(ctx context.Context, token string) (*BaseLimiterConfig, error) {
+	if c.Registry == nil {
+		return &c.BaseLimiterConfig, nil
+	}
+
+	tier, err := c.Registry.Lookup(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenRegistry, err)
+	}
+	if tier == nil {
+		return &c.BaseLimiterConfig, nil
+	}
+	return &tier.BaseLimiterConfig, nil
+}
@@ -0,0 +1,151 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingRegistry struct{}
+
+func (failingRegistry) Lookup(ctx context.Context, token string) (*Tier, error) {
+	return nil, errors.New("key store unavailable")
+}
+
+func newTestRegistry(t *testing.T) *StaticTokenRegistry {
+	registry := NewTokenRegistry(
+		&Tier{Name: "free", BaseLimiterConfig: BaseLimiterConfig{RequestesPerSecond: 1, BlockUserFor: 10 * time.Second}},
+		&Tier{Name: "pro", BaseLimiterConfig: BaseLimiterConfig{RequestesPerSecond: 3, BlockUserFor: 5 * time.Second}},
+		&Tier{Name: "enterprise", BaseLimiterConfig: BaseLimiterConfig{RequestesPerSecond: 100, BlockUserFor: time.Second}},
+	)
+	assert.NoError(t, registry.AssignPrefix("sk_", "free"))
+	assert.NoError(t, registry.AssignPrefix("sk_ent_", "enterprise"))
+	assert.NoError(t, registry.Assign("sk_pro_abc", "pro"))
+	return registry
+}
+
+func TestTokenRegistry(t *testing.T) {
+	t.Run("should prefer exact tokens over the longest matching prefix", func(t *testing.T) {
+		registry := newTestRegistry(t)
+
+		tier, err := registry.Lookup(context.Background(), "sk_pro_abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "pro", tier.Name)
+
+		tier, err = registry.Lookup(context.Background(), "sk_ent_123")
+		assert.NoError(t, err)
+		assert.Equal(t, "enterprise", tier.Name)
+
+		tier, err = registry.Lookup(context.Background(), "sk_other")
+		assert.NoError(t, err)
+		assert.Equal(t, "free", tier.Name)
+
+		tier, err = registry.Lookup(context.Background(), "unknown")
+		assert.NoError(t, err)
+		assert.Nil(t, tier)
+	})
+
+	t.Run("should reject assignments to unknown tiers", func(t *testing.T) {
+		registry := NewTokenRegistry()
+		assert.ErrorIs(t, registry.Assign("abc", "gold"), ErrUnknownTier)
+	})
+
+	t.Run("should apply the limits of the token tier", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		tokenConfig := NewRateLimiterConfigByToken(2, 10*time.Second, "API_KEY")
+		tokenConfig.Registry = newTestRegistry(t)
+		config := NewRateLimiterConfig(nil, tokenConfig)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "sk_pro_abc", config)))
+		}
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "sk_pro_abc", config)), ErrMaxRequests)
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "sk_free", config)))
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "sk_free", config)), ErrMaxRequests)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "unknown", config)))
+		}
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "unknown", config)), ErrMaxRequests)
+	})
+
+	t.Run("should apply the limits of the token tier in redis", func(t *testing.T) {
+		_, client := newTestRedis(t)
+		limiter := NewRedisLimiter(client)
+
+		tokenConfig := NewRateLimiterConfigByToken(10, 10*time.Second, "API_KEY")
+		tokenConfig.Registry = newTestRegistry(t)
+		config := NewRateLimiterConfig(nil, tokenConfig)
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "sk_free", config)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, decision.Limit)
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "sk_free", config)), ErrMaxRequests)
+	})
+
+	t.Run("should return an error when the registry fails", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		tokenConfig := NewRateLimiterConfigByToken(2, 10*time.Second, "API_KEY")
+		tokenConfig.Registry = failingRegistry{}
+		config := NewRateLimiterConfig(nil, tokenConfig)
+
+		err := requestErr(limiter.HandleRequest("127.0.0.1", "abc", config))
+		assert.ErrorIs(t, err, ErrTokenRegistry)
+		assert.NotErrorIs(t, err, ErrGettingRateLimiterData)
+	})
+
+	t.Run("should load the tiers and tokens from a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{
+			"tiers": {
+				"free": {"requestsPerSecond": 5, "blockUserFor": "30s"},
+				"pro": {"requestsPerSecond": 50, "blockUserFor": "10s", "window": "1m", "burst": 100, "refillRate": 20},
+				"enterprise": {"requestsPerSecond": 500, "quota": {"limit": 10000, "period": "month", "timezone": "America/Sao_Paulo"}}
+			},
+			"tokens": {"abc": "pro"},
+			"prefixes": {"free_": "free"}
+		}`), 0o600))
+
+		registry, err := LoadTokenRegistry(path)
+		assert.NoError(t, err)
+
+		tier, err := registry.Lookup(context.Background(), "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, &Tier{Name: "pro", BaseLimiterConfig: BaseLimiterConfig{
+			RequestesPerSecond: 50,
+			BlockUserFor:       10 * time.Second,
+			Window:             time.Minute,
+			Burst:              100,
+			RefillRate:         20,
+		}}, tier)
+
+		tier, err = registry.Lookup(context.Background(), "free_123")
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, tier.BlockUserFor)
+
+		assert.NoError(t, registry.Assign("ent", "enterprise"))
+		tier, err = registry.Lookup(context.Background(), "ent")
+		assert.NoError(t, err)
+		assert.Equal(t, 10000, tier.Quota.Limit)
+		assert.Equal(t, QuotaMonthly, tier.Quota.Period)
+		assert.Equal(t, "America/Sao_Paulo", tier.Quota.Location.String())
+	})
+
+	t.Run("should reject files assigning tokens to unknown tiers", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"tiers": {}, "tokens": {"abc": "pro"}}`), 0o600))
+
+		_, err := LoadTokenRegistry(path)
+		assert.ErrorIs(t, err, ErrUnknownTier)
+	})
+}
@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"math"
+	"time"
+)
+
+type TokenBucket struct{}
+
+func NewTokenBucket() *TokenBucket {
+	return &TokenBucket{}
+}
+
+func (s *TokenBucket) Name() string {
+	return StrategyTokenBucket
+}
+
+func (s *TokenBucket) AllowN(client *ClientRateLimiter, now time.Time, n int) bool {
+	s.refill(client, now)
+
+	if client.Tokens < float64(n) {
+		return false
+	}
+
+	client.Tokens -= float64(n)
+	return true
+}
+
+func (s *TokenBucket) refill(client *ClientRateLimiter, now time.Time) {
+	capacity := float64(client.bucketCapacity())
+
+	if client.LastRefill.IsZero() {
+		client.Tokens = capacity
+	} else if elapsed := now.Sub(client.LastRefill); elapsed > 0 {
+		client.Tokens = math.Min(capacity, client.Tokens+elapsed.Seconds()*client.bucketRefillRate())
+	}
+	client.LastRefill = now
+}
+
+func (s *TokenBucket) Status(client *ClientRateLimiter, now time.Time) (int, int, time.Time) {
+	s.refill(client, now)
+
+	capacity := client.bucketCapacity()
+	available := math.Floor(client.Tokens)
+	if available >= float64(capacity) {
+		return capacity, capacity, now
+	}
+
+	missing := (available + 1 - client.Tokens) / client.bucketRefillRate()
+	return capacity, int(available), now.Add(time.Duration(missing * float64(time.Second)))
+}
+
+func (c *ClientRateLimiter) bucketCapacity() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	return c.RequestsPerSecond
+}
+
+func (c *ClientRateLimiter) bucketRefillRate() float64 {
+	if c.RefillRate > 0 {
+		return c.RefillRate
+	}
+	return float64(c.RequestsPerSecond) / c.window().Seconds()
+}
@@ -0,0 +1,208 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	outboundScope = "outbound"
+	maxResetDelta = 365 * 24 * 60 * 60
+)
+
+var (
+	ErrUpstreamRateLimited = fmt.Errorf("%w: the upstream asked to retry later", ErrMaxRequests)
+	ErrNilOutboundConfig   = errors.New("the outbound limit needs a token config to key the requests")
+	ErrEmptyOutboundKey    = errors.New("the key func returned an empty key for the outbound request")
+)
+
+type KeyFunc func(r *http.Request) string
+
+func KeyByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+type Transport struct {
+	base    http.RoundTripper
+	limiter Limiter
+	config  *RateLimiterConfig
+	key     KeyFunc
+	sleeper Sleeper
+	maxWait time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	backoff map[string]time.Time
+}
+
+type TransportOption func(*Transport)
+
+func WithKeyFunc(key KeyFunc) TransportOption {
+	return func(t *Transport) {
+		t.key = key
+	}
+}
+
+func WithMaxWait(sleeper Sleeper, maxWait time.Duration) TransportOption {
+	return func(t *Transport) {
+		t.sleeper = sleeper
+		t.maxWait = maxWait
+	}
+}
+
+func NewTransport(base http.RoundTripper, limiter Limiter, config *RateLimiterConfig, opts ...TransportOption) (*Transport, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.ConfigByToken == nil {
+		return nil, ErrNilOutboundConfig
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	scoped := *config
+	scoped.ConfigByIP = nil
+	if scoped.Scope == "" {
+		scoped.Scope = outboundScope
+	}
+
+	t := &Transport{
+		base:    base,
+		limiter: limiter,
+		config:  &scoped,
+		key:     KeyByHost,
+		now:     time.Now,
+		backoff: make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.maxWait > 0 {
+		t.limiter = NewWaitingLimiter(t.limiter, t.sleeper, t.maxWait)
+	}
+
+	return t, nil
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := t.key(r)
+	if key == "" {
+		closeBody(r)
+		return nil, ErrEmptyOutboundKey
+	}
+
+	if err := t.waitForUpstream(r, key); err != nil {
+		closeBody(r)
+		return nil, err
+	}
+
+	if _, err := t.limiter.HandleRequestContext(r.Context(), "", key, t.config); err != nil {
+		closeBody(r)
+		return nil, err
+	}
+
+	response, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t.observe(key, response)
+
+	return response, nil
+}
+
+func (t *Transport) waitForUpstream(r *http.Request, key string) error {
+	t.mu.Lock()
+	until, ok := t.backoff[key]
+	t.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	wait := until.Sub(t.now())
+	if wait <= 0 {
+		return nil
+	}
+
+	if wait > t.maxWait || !canWait(r.Context(), wait) {
+		return fmt.Errorf("%w: retry after %s", ErrUpstreamRateLimited, wait.Round(time.Second))
+	}
+
+	if !sleepContext(r.Context(), t.sleeper, wait) {
+		return r.Context().Err()
+	}
+	return nil
+}
+
+func closeBody(r *http.Request) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+}
+
+func (t *Transport) observe(key string, response *http.Response) {
+	now := t.now()
+	wait, ok := upstreamBackoff(response, now)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ok && wait > 0 {
+		t.backoff[key] = now.Add(wait)
+	} else {
+		delete(t.backoff, key)
+	}
+}
+
+func upstreamBackoff(response *http.Response, now time.Time) (time.Duration, bool) {
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		if wait, ok := parseRetryAfter(response.Header.Get("Retry-After"), now); ok {
+			return wait, true
+		}
+	}
+
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		remaining := response.Header.Get(prefix + "Remaining")
+		if remaining == "" {
+			continue
+		}
+		if count, err := strconv.Atoi(remaining); err != nil || count > 0 {
+			return 0, false
+		}
+		return parseReset(response.Header.Get(prefix+"Reset"), now)
+	}
+
+	return 0, false
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now), true
+	}
+	return 0, false
+}
+
+func parseReset(value string, now time.Time) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if seconds > maxResetDelta {
+		return time.Unix(seconds, 0).Sub(now), true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,216 @@
+package ratelimiter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransport(t *testing.T, config *RateLimiterConfig, maxWait time.Duration, opts ...TransportOption) (*http.Client, *clockSleeper) {
+	sleeper := newClockSleeper()
+
+	limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(sleeper.clock.Now))
+	t.Cleanup(limiter.Stop)
+
+	transport, err := NewTransport(nil, limiter, config, append(opts, WithMaxWait(sleeper, maxWait))...)
+	assert.NoError(t, err)
+	transport.now = sleeper.clock.Now
+
+	return &http.Client{Transport: transport}, sleeper
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestTransport(t *testing.T) {
+	config := NewRateLimiterConfig(nil, NewRateLimiterConfigByToken(2, 0, ""))
+
+	t.Run("should fail when the outbound limit of the host is reached", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+		}))
+		defer server.Close()
+
+		client, _ := newTestTransport(t, config, 0)
+
+		for i := 0; i < 2; i++ {
+			response, err := client.Get(server.URL)
+			assert.NoError(t, err)
+			response.Body.Close()
+		}
+
+		_, err := client.Get(server.URL)
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should wait for capacity when a max wait is configured", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		client, sleeper := newTestTransport(t, config, 5*time.Second)
+
+		for i := 0; i < 3; i++ {
+			response, err := client.Get(server.URL)
+			assert.NoError(t, err)
+			response.Body.Close()
+		}
+		assert.Equal(t, []time.Duration{time.Second}, sleeper.slept)
+	})
+
+	t.Run("should use a custom key func", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		client, _ := newTestTransport(t, config, 0, WithKeyFunc(func(r *http.Request) string {
+			return r.URL.Path
+		}))
+
+		for _, path := range []string{"/a", "/a", "/b"} {
+			response, err := client.Get(server.URL + path)
+			assert.NoError(t, err)
+			response.Body.Close()
+		}
+
+		_, err := client.Get(server.URL + "/a")
+		assert.ErrorIs(t, err, ErrMaxRequests)
+	})
+
+	t.Run("should honor the retry after of the upstream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/busy" {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer server.Close()
+
+		client, sleeper := newTestTransport(t, NewRateLimiterConfig(nil, NewRateLimiterConfigByToken(100, 0, "")), 0)
+
+		response, err := client.Get(server.URL + "/busy")
+		assert.NoError(t, err)
+		response.Body.Close()
+
+		_, err = client.Get(server.URL)
+		assert.ErrorIs(t, err, ErrUpstreamRateLimited)
+
+		sleeper.clock.Advance(30 * time.Second)
+		response, err = client.Get(server.URL)
+		assert.NoError(t, err)
+		response.Body.Close()
+	})
+
+	t.Run("should wait for the ratelimit reset of the upstream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "3")
+		}))
+		defer server.Close()
+
+		client, sleeper := newTestTransport(t, NewRateLimiterConfig(nil, NewRateLimiterConfigByToken(100, 0, "")), 5*time.Second)
+
+		for i := 0; i < 2; i++ {
+			response, err := client.Get(server.URL)
+			assert.NoError(t, err)
+			response.Body.Close()
+		}
+		assert.Equal(t, []time.Duration{3 * time.Second}, sleeper.slept)
+	})
+
+	t.Run("should close the request body when the call is refused", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		client, _ := newTestTransport(t, NewRateLimiterConfig(nil, NewRateLimiterConfigByToken(1, 0, "")), 0)
+
+		response, err := client.Post(server.URL, "text/plain", strings.NewReader("first"))
+		assert.NoError(t, err)
+		response.Body.Close()
+
+		body := &closeRecorder{Reader: strings.NewReader("second")}
+		_, err = client.Post(server.URL, "text/plain", body)
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.True(t, body.closed)
+	})
+
+	t.Run("should keep a limit per host without an ip limit", func(t *testing.T) {
+		first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer first.Close()
+		second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer second.Close()
+
+		withIP := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, 0), NewRateLimiterConfigByToken(1, 0, ""))
+		withIP.Mode = CombineAll
+		client, _ := newTestTransport(t, withIP, 0)
+
+		for _, url := range []string{first.URL, second.URL} {
+			response, err := client.Get(url)
+			assert.NoError(t, err)
+			response.Body.Close()
+		}
+	})
+
+	t.Run("should refuse a request without a key", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		client, _ := newTestTransport(t, config, 0, WithKeyFunc(func(r *http.Request) string {
+			return ""
+		}))
+
+		body := &closeRecorder{Reader: strings.NewReader("payload")}
+		_, err := client.Post(server.URL, "text/plain", body)
+		assert.ErrorIs(t, err, ErrEmptyOutboundKey)
+		assert.True(t, body.closed)
+	})
+
+	t.Run("should require a config with a token limit", func(t *testing.T) {
+		limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer limiter.Stop()
+
+		_, err := NewTransport(nil, limiter, nil)
+		assert.ErrorIs(t, err, ErrNilConfig)
+
+		_, err = NewTransport(nil, limiter, NewRateLimiterConfig(NewRateLimiterConfigByIP(2, 0), nil))
+		assert.ErrorIs(t, err, ErrNilOutboundConfig)
+	})
+
+	t.Run("should parse the upstream backoff headers", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		response := func(status int, headers ...string) *http.Response {
+			r := &http.Response{StatusCode: status, Header: http.Header{}}
+			for i := 0; i+1 < len(headers); i += 2 {
+				r.Header.Set(headers[i], headers[i+1])
+			}
+			return r
+		}
+
+		wait, ok := upstreamBackoff(response(http.StatusServiceUnavailable, "Retry-After", now.Add(time.Minute).Format(http.TimeFormat)), now)
+		assert.True(t, ok)
+		assert.Equal(t, time.Minute, wait)
+
+		wait, ok = upstreamBackoff(response(http.StatusOK, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1704067210"), now)
+		assert.True(t, ok)
+		assert.Equal(t, 10*time.Second, wait)
+
+		_, ok = upstreamBackoff(response(http.StatusOK, "RateLimit-Remaining", "5", "RateLimit-Reset", "10"), now)
+		assert.False(t, ok)
+
+		_, ok = upstreamBackoff(response(http.StatusOK), now)
+		assert.False(t, ok)
+	})
+}
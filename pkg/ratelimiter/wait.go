@@ -0,0 +1,107 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const minWait = 10 * time.Millisecond
+
+type peeker interface {
+	peekN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error)
+}
+
+type WaitingLimiter struct {
+	limiter  Limiter
+	sleeper  Sleeper
+	maxDelay time.Duration
+}
+
+func NewWaitingLimiter(limiter Limiter, sleeper Sleeper, maxDelay time.Duration) *WaitingLimiter {
+	return &WaitingLimiter{limiter: limiter, sleeper: sleeper, maxDelay: maxDelay}
+}
+
+func (l *WaitingLimiter) Unwrap() Limiter {
+	return l.limiter
+}
+
+func (l *WaitingLimiter) HandleRequest(ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestContext(context.Background(), ip, token, config)
+}
+
+func (l *WaitingLimiter) HandleRequestContext(ctx context.Context, ip, token string, config *RateLimiterConfig) (*Decision, error) {
+	return l.HandleRequestN(ctx, ip, token, config, 1)
+}
+
+func (l *WaitingLimiter) HandleRequestN(ctx context.Context, ip, token string, config *RateLimiterConfig, cost int) (*Decision, error) {
+	charge := func() (*Decision, error) {
+		return l.limiter.HandleRequestN(ctx, ip, token, config, cost)
+	}
+
+	limiter, ok := l.limiter.(peeker)
+	if !ok {
+		return l.waitFor(ctx, charge)
+	}
+
+	decision, err := l.waitFor(ctx, func() (*Decision, error) {
+		return limiter.peekN(ctx, ip, token, config, cost)
+	})
+
+	if err != nil && !errors.Is(err, ErrMaxRequests) {
+		return l.waitFor(ctx, charge)
+	}
+	if ctx.Err() != nil {
+		return decision, err
+	}
+
+	return charge()
+}
+
+func (l *WaitingLimiter) waitFor(ctx context.Context, attempt func() (*Decision, error)) (*Decision, error) {
+	var waited time.Duration
+
+	for {
+		decision, err := attempt()
+		if !errors.Is(err, ErrMaxRequests) {
+			return decision, err
+		}
+
+		wait := decision.RetryAfter
+		if wait < minWait {
+			wait = minWait
+		}
+
+		if waited+wait > l.maxDelay || !canWait(ctx, wait) || !sleepContext(ctx, l.sleeper, wait) {
+			return decision, err
+		}
+
+		waited += wait
+	}
+}
+
+func canWait(ctx context.Context, wait time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		return false
+	}
+	return true
+}
+
+func sleepContext(ctx context.Context, sleeper Sleeper, wait time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		sleeper.Sleep(wait)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type clockSleeper struct {
+	clock *testClock
+	slept []time.Duration
+}
+
+func newClockSleeper() *clockSleeper {
+	return &clockSleeper{clock: newTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+}
+
+func (s *clockSleeper) Sleep(d time.Duration) {
+	s.clock.Advance(d)
+	s.slept = append(s.slept, d)
+}
+
+type cancelingSleeper struct {
+	cancel  context.CancelFunc
+	release chan struct{}
+}
+
+func (s *cancelingSleeper) Sleep(d time.Duration) {
+	s.cancel()
+	<-s.release
+}
+
+func newWaitingTestLimiter(t *testing.T, maxDelay time.Duration) (*WaitingLimiter, *clockSleeper) {
+	sleeper := newClockSleeper()
+
+	limiter := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(sleeper.clock.Now))
+	t.Cleanup(limiter.Stop)
+
+	return NewWaitingLimiter(limiter, sleeper, maxDelay), sleeper
+}
+
+type sleeperFunc func(d time.Duration)
+
+func (f sleeperFunc) Sleep(d time.Duration) {
+	f(d)
+}
+
+func TestWaitingLimiter(t *testing.T) {
+	config := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, 0), nil)
+
+	t.Run("should wait until capacity is available", func(t *testing.T) {
+		limiter, sleeper := newWaitingTestLimiter(t, 5*time.Second)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		}
+		assert.Equal(t, []time.Duration{time.Second}, sleeper.slept)
+	})
+
+	t.Run("should reject when the wait would exceed the max delay", func(t *testing.T) {
+		limiter, sleeper := newWaitingTestLimiter(t, 500*time.Millisecond)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		}
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "", config)
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.Equal(t, time.Second, decision.RetryAfter)
+		assert.Empty(t, sleeper.slept)
+	})
+
+	t.Run("should reject when the wait would exceed the context deadline", func(t *testing.T) {
+		limiter, sleeper := newWaitingTestLimiter(t, 5*time.Second)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequestContext(ctx, "127.0.0.1", "", config)), ErrMaxRequests)
+		assert.Empty(t, sleeper.slept)
+	})
+
+	t.Run("should not wait for a blocked client", func(t *testing.T) {
+		limiter, sleeper := newWaitingTestLimiter(t, 5*time.Second)
+		blocking := NewRateLimiterConfig(NewRateLimiterConfigByIP(1, time.Minute), nil)
+		blocking.ConfigByIP.Window = 10 * time.Second
+
+		assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", blocking)))
+
+		decision, err := limiter.HandleRequest("127.0.0.1", "", blocking)
+		assert.ErrorIs(t, err, ErrMaxRequests)
+		assert.Equal(t, time.Minute, decision.RetryAfter)
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", blocking)), ErrMaxRequests)
+		assert.Empty(t, sleeper.slept)
+	})
+
+	t.Run("should wait for the window instead of the block penalty", func(t *testing.T) {
+		limiter, sleeper := newWaitingTestLimiter(t, 5*time.Second)
+		blocking := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, time.Minute), nil)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", blocking)))
+		}
+		assert.Equal(t, []time.Duration{time.Second}, sleeper.slept)
+	})
+
+	t.Run("should wait for the window through the resilient limiter", func(t *testing.T) {
+		sleeper := newClockSleeper()
+		inner := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper(), WithClock(sleeper.clock.Now))
+		t.Cleanup(inner.Stop)
+
+		resilient := NewResilientLimiter(inner, NewCircuitBreaker(5, time.Minute), FailClosed)
+		limiter := NewWaitingLimiter(resilient, sleeper, 5*time.Second)
+		blocking := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, time.Minute), nil)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", blocking)))
+		}
+		assert.Equal(t, []time.Duration{time.Second}, sleeper.slept)
+	})
+
+	t.Run("should wait for the window of the redis limiter", func(t *testing.T) {
+		server, client := newTestRedis(t)
+		sleeper := newClockSleeper()
+		limiter := NewWaitingLimiter(NewRedisLimiter(client, WithClock(sleeper.clock.Now)), sleeperFunc(func(d time.Duration) {
+			sleeper.Sleep(d)
+			server.SetTime(sleeper.clock.Now())
+		}), 5*time.Second)
+		blocking := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, time.Minute), nil)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", blocking)))
+		}
+		assert.Equal(t, []time.Duration{time.Second}, sleeper.slept)
+	})
+
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sleeper := &cancelingSleeper{cancel: cancel, release: make(chan struct{})}
+		defer close(sleeper.release)
+
+		inner := NewRateLimiter(NewInMemoryDatasource(), NewTimeSleeper())
+		defer inner.Stop()
+		limiter := NewWaitingLimiter(inner, sleeper, 5*time.Second)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		}
+		assert.ErrorIs(t, requestErr(limiter.HandleRequestContext(ctx, "127.0.0.1", "", config)), ErrMaxRequests)
+	})
+
+	t.Run("should not retry on datasource errors", func(t *testing.T) {
+		failing := &failingLimiter{err: ErrGettingRateLimiterData}
+		limiter := NewWaitingLimiter(failing, newClockSleeper(), 5*time.Second)
+
+		assert.ErrorIs(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)), ErrGettingRateLimiterData)
+		assert.Equal(t, 1, failing.calls)
+	})
+
+	t.Run("should not reach the datasource while the breaker is open", func(t *testing.T) {
+		failing := &failingLimiter{err: ErrGettingRateLimiterData}
+		resilient := NewResilientLimiter(failing, NewCircuitBreaker(1, time.Minute), FailOpen)
+		limiter := NewWaitingLimiter(resilient, newClockSleeper(), 5*time.Second)
+
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", config)))
+		}
+		assert.Equal(t, 1, failing.peeks)
+		assert.Equal(t, 1, failing.calls)
+	})
+
+	t.Run("should wait on the local fallback while the breaker is open", func(t *testing.T) {
+		failing := &failingLimiter{err: ErrGettingRateLimiterData}
+		sleeper := newClockSleeper()
+		resilient := NewResilientLimiter(failing, NewCircuitBreaker(1, time.Minute), FailLocal, WithClock(sleeper.clock.Now))
+		limiter := NewWaitingLimiter(resilient, sleeper, 5*time.Second)
+		blocking := NewRateLimiterConfig(NewRateLimiterConfigByIP(2, time.Minute), nil)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, requestErr(limiter.HandleRequest("127.0.0.1", "", blocking)))
+		}
+		assert.Equal(t, []time.Duration{time.Second}, sleeper.slept)
+		assert.Equal(t, 1, failing.calls)
+	})
+}